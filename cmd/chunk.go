@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"strings"
+)
+
+// Units chunk sizes are measured in
+const (
+	chunkByLines  = "lines"
+	chunkByTokens = "tokens"
+)
+
+// Default chunk sizes per unit; the default overlap is a tenth of the size
+var defaultChunkSize = map[string]int{
+	chunkByLines:  100,
+	chunkByTokens: 512,
+}
+
+// chunkRecord is one line of a --chunks JSONL file
+type chunkRecord struct {
+	Path      string `json:"path"`
+	Language  string `json:"language"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Tokens    int    `json:"tokens"`
+	Hash      string `json:"hash"`
+	Content   string `json:"content"`
+}
+
+// chunkSpan is a range of 0-based line indexes, end exclusive
+type chunkSpan struct {
+	start, end int
+}
+
+// chunkSpans packs lines into chunks of at most size units, repeating up
+// to overlap units of each chunk at the start of the next. A chunk only
+// ends before line i when breaks[i] is set, unless a single unbroken run
+// of lines is larger than size.
+func chunkSpans(weights []int, breaks []bool, size, overlap int) []chunkSpan {
+	var spans []chunkSpan
+	for start, prevEnd := 0, 0; start < len(weights); {
+		end, _ := chunkEnd(weights, breaks, start, prevEnd, size)
+		spans = append(spans, chunkSpan{start, end})
+		if end == len(weights) {
+			break
+		}
+
+		// Step back over the overlap, unless the overlapping chunk could
+		// not reach a break past this one and would only repeat it
+		next, repeated := end, 0
+		for next > start+1 && repeated+weights[next-1] <= overlap {
+			next--
+			repeated += weights[next]
+		}
+		if _, ok := chunkEnd(weights, breaks, next, end, size); !ok {
+			next = end
+		}
+		start, prevEnd = next, end
+	}
+	return spans
+}
+
+// chunkEnd returns where a chunk starting at start ends: at the last break
+// after the line index after that fits in size, or at size itself when
+// there is none, in which case ok is false
+func chunkEnd(weights []int, breaks []bool, start, after, size int) (end int, ok bool) {
+	n := len(weights)
+	total, lastBreak := 0, -1
+	for end = start; end < n && (end == start || total+weights[end] <= size); {
+		total += weights[end]
+		end++
+		if end > after && (end == n || breaks[end]) {
+			lastBreak = end
+		}
+	}
+	if end == n {
+		return n, true
+	}
+	if lastBreak > start {
+		return lastBreak, true
+	}
+	return end, false
+}
+
+// goBreaks marks the lines of Go source a chunk may start at: anywhere
+// outside a top-level declaration and its doc comment. Source that does
+// not parse may be split anywhere.
+func goBreaks(source string, lineCount int) []bool {
+	breaks := make([]bool, lineCount)
+	for i := range breaks {
+		breaks[i] = true
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", source, parser.ParseComments)
+	if err != nil {
+		return breaks
+	}
+	for _, decl := range file.Decls {
+		start := decl.Pos()
+		var doc *ast.CommentGroup
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			doc = d.Doc
+		case *ast.GenDecl:
+			doc = d.Doc
+		}
+		if doc != nil {
+			start = doc.Pos()
+		}
+		first := fset.Position(start).Line - 1
+		last := fset.Position(decl.End()).Line - 1
+		for i := first + 1; i <= last && i < lineCount; i++ {
+			breaks[i] = false
+		}
+	}
+	return breaks
+}
+
+// chunkFile splits one file into chunk records
+func chunkFile(section *fileSection, by string, size, overlap int) []chunkRecord {
+	lines := splitLines(section.text())
+	if len(lines) == 0 {
+		return nil
+	}
+
+	weights := make([]int, len(lines))
+	for i, line := range lines {
+		weights[i] = 1
+		if by == chunkByTokens {
+			weights[i] = estimateTokens(line)
+		}
+	}
+	var breaks []bool
+	if strings.HasSuffix(section.Path, ".go") {
+		breaks = goBreaks(section.Source, len(lines))
+	} else {
+		breaks = make([]bool, len(lines))
+		for i := range breaks {
+			breaks[i] = true
+		}
+	}
+
+	var records []chunkRecord
+	for _, span := range chunkSpans(weights, breaks, size, overlap) {
+		content := strings.Join(lines[span.start:span.end], "")
+		records = append(records, chunkRecord{
+			Path:      section.Path,
+			Language:  section.Lang,
+			StartLine: span.start + 1,
+			EndLine:   span.end,
+			Tokens:    estimateTokens(content),
+			Hash:      hashContent(content),
+			Content:   content,
+		})
+	}
+	return records
+}
+
+// writeChunks writes the chunks of every file with content as JSONL and
+// returns the number of chunks written
+func writeChunks(outputPath string, files []*fileSection, opts *rollupOptions) (int, error) {
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return 0, fmt.Errorf("error creating chunks file: %v", err)
+	}
+	defer out.Close()
+
+	count := 0
+	enc := json.NewEncoder(out)
+	for _, f := range files {
+		for _, record := range chunkFile(f, opts.chunkBy, opts.chunkSize, opts.chunkOverlap) {
+			if err := enc.Encode(record); err != nil {
+				return count, fmt.Errorf("error writing chunks: %v", err)
+			}
+			count++
+		}
+	}
+	return count, nil
+}
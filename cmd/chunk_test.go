@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChunkSpans(t *testing.T) {
+	all := func(n int) []bool {
+		b := make([]bool, n)
+		for i := range b {
+			b[i] = true
+		}
+		return b
+	}
+	ones := func(n int) []int {
+		w := make([]int, n)
+		for i := range w {
+			w[i] = 1
+		}
+		return w
+	}
+
+	tests := []struct {
+		name    string
+		weights []int
+		breaks  []bool
+		size    int
+		overlap int
+		want    []chunkSpan
+	}{
+		{
+			name:    "fits in one chunk",
+			weights: ones(5),
+			breaks:  all(5),
+			size:    10,
+			want:    []chunkSpan{{0, 5}},
+		},
+		{
+			name:    "no overlap",
+			weights: ones(10),
+			breaks:  all(10),
+			size:    4,
+			want:    []chunkSpan{{0, 4}, {4, 8}, {8, 10}},
+		},
+		{
+			name:    "overlap",
+			weights: ones(10),
+			breaks:  all(10),
+			size:    4,
+			overlap: 1,
+			want:    []chunkSpan{{0, 4}, {3, 7}, {6, 10}},
+		},
+		{
+			name:    "weighted",
+			weights: []int{3, 3, 3, 1, 1},
+			breaks:  all(5),
+			size:    6,
+			want:    []chunkSpan{{0, 2}, {2, 5}},
+		},
+		{
+			name:    "ends at breaks",
+			weights: ones(8),
+			breaks:  []bool{true, false, false, true, false, false, true, false},
+			size:    4,
+			want:    []chunkSpan{{0, 3}, {3, 6}, {6, 8}},
+		},
+		{
+			name:    "splits runs larger than size",
+			weights: ones(8),
+			breaks:  []bool{true, false, false, false, false, false, false, false},
+			size:    3,
+			want:    []chunkSpan{{0, 3}, {3, 6}, {6, 8}},
+		},
+		{
+			name:    "skips overlap that cannot reach a new break",
+			weights: ones(12),
+			breaks:  []bool{true, false, false, true, false, false, false, true, false, false, false, false},
+			size:    4,
+			overlap: 2,
+			want:    []chunkSpan{{0, 3}, {3, 7}, {7, 11}, {9, 12}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := chunkSpans(tt.weights, tt.breaks, tt.size, tt.overlap)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chunkSpans() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoBreaks(t *testing.T) {
+	source := `package main
+
+// add adds
+func add(a, b int) int {
+	return a + b
+}
+
+var x = 1
+`
+	want := []bool{true, true, true, false, false, false, true, true}
+	if got := goBreaks(source, 8); !reflect.DeepEqual(got, want) {
+		t.Errorf("goBreaks() = %v, want %v", got, want)
+	}
+
+	// Source that does not parse can be split anywhere
+	for i, ok := range goBreaks("func {\n}\n", 2) {
+		if !ok {
+			t.Errorf("goBreaks() of invalid source blocks line %d", i)
+		}
+	}
+}
+
+func TestChunkFile(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("package main\n\n")
+	for _, name := range []string{"one", "two", "three"} {
+		b.WriteString("func " + name + "() {\n\tprintln()\n\tprintln()\n}\n\n")
+	}
+	section := &fileSection{Path: "main.go", Lang: "go", Source: b.String()}
+
+	records := chunkFile(section, chunkByLines, 8, 0)
+	var got []string
+	for _, r := range records {
+		got = append(got, strings.SplitN(r.Content, "\n", 2)[0])
+		if r.Path != "main.go" || r.Language != "go" || r.Hash != hashContent(r.Content) {
+			t.Errorf("record fields = %+v", r)
+		}
+		if lines := countLines(r.Content); lines != r.EndLine-r.StartLine+1 {
+			t.Errorf("record %d-%d holds %d lines", r.StartLine, r.EndLine, lines)
+		}
+	}
+	want := []string{"package main", "func two() {", "func three() {"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("chunks start with %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Defaults for data files: those smaller than defaultDataThreshold go in
+// as they are, larger tables are cut to their first and random rows
+const (
+	defaultDataThreshold = "64KB"
+	defaultSampleRows    = 10
+	defaultRandomRows    = 10
+)
+
+// Limits on how much of a document's structure a schema shows
+const (
+	schemaMaxDepth = 12
+	schemaMaxKeys  = 50
+)
+
+// sampleTable renders a CSV or TSV file as its header, its first head
+// rows and random rows picked from the rest. The random rows are the same
+// on every run for the same content.
+func sampleTable(name, content string, head, random int) (string, error) {
+	r := csv.NewReader(strings.NewReader(content))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	kind := "CSV"
+	if strings.EqualFold(strings.TrimPrefix(filepath.Ext(name), "."), "tsv") {
+		r.Comma = '\t'
+		kind = "TSV"
+	}
+	records, err := r.ReadAll()
+	if err != nil {
+		return "", err
+	}
+	if len(records) == 0 {
+		return "", errors.New("no rows")
+	}
+	header, rows := records[0], records[1:]
+
+	picked := make([]int, 0, head+random)
+	for i := 0; i < head && i < len(rows); i++ {
+		picked = append(picked, i)
+	}
+	var sampled []int
+	if rest := len(rows) - len(picked); rest > 0 && random > 0 {
+		rng := rand.New(rand.NewSource(contentSeed(content)))
+		for _, i := range rng.Perm(rest)[:min(random, rest)] {
+			sampled = append(sampled, len(picked)+i)
+		}
+		sort.Ints(sampled)
+		picked = append(picked, sampled...)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s file of %s with %d rows and %d columns", kind, humanReadableSize(int64(len(content))), len(rows), len(header))
+	switch {
+	case len(picked) == len(rows):
+		b.WriteString(": the header and every row.\n\n")
+	case len(sampled) == 0:
+		fmt.Fprintf(&b, ": the header and the first %d rows.\n\n", len(picked))
+	default:
+		numbers := make([]string, len(sampled))
+		for i, row := range sampled {
+			numbers[i] = strconv.Itoa(row + 1)
+		}
+		fmt.Fprintf(&b, ": the header, the first %d rows and rows %s picked at random.\n\n",
+			len(picked)-len(sampled), strings.Join(numbers, ", "))
+	}
+
+	var out bytes.Buffer
+	w := csv.NewWriter(&out)
+	w.Comma = r.Comma
+	w.Write(header)
+	for _, i := range picked {
+		w.Write(rows[i])
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", err
+	}
+	b.WriteString(fencedBlock(out.String(), strings.ToLower(kind)))
+	return b.String(), nil
+}
+
+// contentSeed derives a random seed from content, so samples only change
+// when the file does
+func contentSeed(content string) int64 {
+	sum, _ := hex.DecodeString(hashContent(content)[:16])
+	return int64(binary.BigEndian.Uint64(sum))
+}
+
+// schemaNode describes the values found at one place in a document. Values
+// of arrays are merged, so a node can have several types and a range of
+// array lengths.
+type schemaNode struct {
+	types map[string]bool
+
+	// fields holds the keys of objects
+	fields map[string]*schemaNode
+
+	// items describes the elements of arrays, whose lengths range from
+	// minLen to maxLen
+	items          *schemaNode
+	minLen, maxLen int
+}
+
+// schemaTypes orders the types of a node
+var schemaTypes = []string{"object", "array", "string", "number", "boolean", "null"}
+
+func (n *schemaNode) add(v interface{}) {
+	if n.types == nil {
+		n.types = make(map[string]bool)
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		n.addObject()
+		for key, value := range v {
+			n.field(key).add(value)
+		}
+	case map[interface{}]interface{}:
+		n.addObject()
+		for key, value := range v {
+			n.field(fmt.Sprint(key)).add(value)
+		}
+	case []interface{}:
+		if !n.types["array"] {
+			n.minLen, n.maxLen = len(v), len(v)
+			n.items = &schemaNode{}
+		}
+		n.types["array"] = true
+		n.minLen, n.maxLen = min(n.minLen, len(v)), max(n.maxLen, len(v))
+		for _, item := range v {
+			n.items.add(item)
+		}
+	case string:
+		n.types["string"] = true
+	case bool:
+		n.types["boolean"] = true
+	case nil:
+		n.types["null"] = true
+	case json.Number, int, int64, uint64, float64:
+		n.types["number"] = true
+	default:
+		// YAML timestamps and other scalars
+		n.types["string"] = true
+	}
+}
+
+func (n *schemaNode) addObject() {
+	n.types["object"] = true
+	if n.fields == nil {
+		n.fields = make(map[string]*schemaNode)
+	}
+}
+
+func (n *schemaNode) field(key string) *schemaNode {
+	if n.fields[key] == nil {
+		n.fields[key] = &schemaNode{}
+	}
+	return n.fields[key]
+}
+
+// describe names the types of a node, with the lengths of its arrays
+func (n *schemaNode) describe() string {
+	var types []string
+	for _, t := range schemaTypes {
+		if !n.types[t] {
+			continue
+		}
+		switch {
+		case t == "object":
+			types = append(types, fmt.Sprintf("object (%d keys)", len(n.fields)))
+		case t == "array" && n.minLen == n.maxLen:
+			types = append(types, fmt.Sprintf("array (%d items)", n.maxLen))
+		case t == "array":
+			types = append(types, fmt.Sprintf("array (%d-%d items)", n.minLen, n.maxLen))
+		default:
+			types = append(types, t)
+		}
+	}
+	return strings.Join(types, " | ")
+}
+
+// write renders the node and what it holds as an indented outline
+func (n *schemaNode) write(b *strings.Builder, label string, depth int) {
+	indent := strings.Repeat("  ", depth)
+	fmt.Fprintf(b, "%s%s: %s\n", indent, label, n.describe())
+	if depth >= schemaMaxDepth {
+		if len(n.fields) > 0 || (n.items != nil && n.items.types != nil) {
+			fmt.Fprintf(b, "%s  ...\n", indent)
+		}
+		return
+	}
+
+	keys := make([]string, 0, len(n.fields))
+	for key := range n.fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		if i == schemaMaxKeys {
+			fmt.Fprintf(b, "%s  ... (%d more keys)\n", indent, len(keys)-schemaMaxKeys)
+			break
+		}
+		n.fields[key].write(b, key, depth+1)
+	}
+	if n.items != nil && n.items.types != nil {
+		n.items.write(b, "[]", depth+1)
+	}
+}
+
+// summarizeSchema renders a JSON or YAML document as the outline of its
+// keys, value types and array lengths. The documents of a YAML stream are
+// merged.
+func summarizeSchema(name, content string) (string, error) {
+	root := &schemaNode{}
+	kind, documents := "JSON", 0
+	switch strings.ToLower(strings.TrimPrefix(filepath.Ext(name), ".")) {
+	case "yaml", "yml":
+		kind = "YAML"
+		dec := yaml.NewDecoder(strings.NewReader(content))
+		for {
+			var v interface{}
+			err := dec.Decode(&v)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", err
+			}
+			root.add(v)
+			documents++
+		}
+	default:
+		dec := json.NewDecoder(strings.NewReader(content))
+		dec.UseNumber()
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return "", err
+		}
+		root.add(v)
+		documents = 1
+	}
+	if documents == 0 {
+		return "", errors.New("no documents")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s file of %s", kind, humanReadableSize(int64(len(content))))
+	if documents > 1 {
+		fmt.Fprintf(&b, " with %d documents", documents)
+	}
+	b.WriteString(": the schema of its keys, value types and array lengths.\n\n")
+	var outline strings.Builder
+	root.write(&outline, "$", 0)
+	b.WriteString(fencedBlock(outline.String(), "text"))
+	return b.String(), nil
+}
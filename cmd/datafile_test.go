@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSampleTable(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("id,name\n")
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(&b, "%d,row %d\n", i, i)
+	}
+	content := b.String()
+
+	got, err := sampleTable("data.csv", content, 3, 4)
+	if err != nil {
+		t.Fatalf("sampleTable() returned error: %v", err)
+	}
+	if again, _ := sampleTable("data.csv", content, 3, 4); again != got {
+		t.Errorf("sampleTable() picked different rows on a second run")
+	}
+	description, block, _ := strings.Cut(got, "\n\n")
+	if !strings.HasPrefix(description, "CSV file of 992 B with 100 rows and 2 columns: the header, the first 3 rows and rows ") {
+		t.Errorf("sampleTable() description = %q", description)
+	}
+	lines := strings.Split(strings.TrimSuffix(block, "\n"), "\n")
+	if len(lines) != 10 || lines[0] != "```csv" || lines[1] != "id,name" || lines[4] != "3,row 3" || lines[9] != "```" {
+		t.Fatalf("sampleTable() block = %q", lines)
+	}
+	previous := 3
+	for _, row := range lines[5:9] {
+		var id int
+		fmt.Sscanf(row, "%d,", &id)
+		if id <= previous {
+			t.Errorf("sampleTable() sampled row %q out of order or from the first rows", row)
+		}
+		previous = id
+	}
+	if !strings.Contains(description, fmt.Sprintf(" %d picked at random", previous)) {
+		t.Errorf("sampleTable() description %q does not list the sampled rows", description)
+	}
+
+	tests := []struct {
+		name         string
+		content      string
+		head, random int
+		want         string
+	}{
+		{"small.csv", "a,b\n1,2\n3,4\n", 10, 10, "CSV file of 12 B with 2 rows and 2 columns: the header and every row.\n\n```csv\na,b\n1,2\n3,4\n```\n"},
+		{"head.tsv", "a\tb\n1\t2\n3\t4\n", 1, 0, "TSV file of 12 B with 2 rows and 2 columns: the header and the first 1 rows.\n\n```tsv\na\tb\n1\t2\n```\n"},
+	}
+	for _, tt := range tests {
+		got, err := sampleTable(tt.name, tt.content, tt.head, tt.random)
+		if err != nil {
+			t.Fatalf("sampleTable(%s) returned error: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("sampleTable(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSummarizeSchema(t *testing.T) {
+	doc := `{"users": [
+		{"id": 1, "name": "a", "tags": ["x", "y"], "manager": null},
+		{"id": 2, "name": "b", "tags": [], "active": true}
+	], "total": 2}`
+	got, err := summarizeSchema("users.json", doc)
+	if err != nil {
+		t.Fatalf("summarizeSchema() returned error: %v", err)
+	}
+	want := "JSON file of 143 B: the schema of its keys, value types and array lengths.\n\n" +
+		"```text\n" +
+		"$: object (2 keys)\n" +
+		"  total: number\n" +
+		"  users: array (2 items)\n" +
+		"    []: object (5 keys)\n" +
+		"      active: boolean\n" +
+		"      id: number\n" +
+		"      manager: null\n" +
+		"      name: string\n" +
+		"      tags: array (0-2 items)\n" +
+		"        []: string\n" +
+		"```\n"
+	if got != want {
+		t.Errorf("summarizeSchema() = %q, want %q", got, want)
+	}
+
+	got, err = summarizeSchema("docs.yaml", "name: a\nport: 80\n---\nname: b\nport: http\n")
+	if err != nil {
+		t.Fatalf("summarizeSchema() for YAML returned error: %v", err)
+	}
+	if !strings.Contains(got, " with 2 documents:") || !strings.Contains(got, "  port: string | number\n") {
+		t.Errorf("summarizeSchema() for YAML = %q", got)
+	}
+
+	if _, err := summarizeSchema("bad.json", "{"); err == nil {
+		t.Errorf("summarizeSchema() for invalid JSON returned no error")
+	}
+}
+
+func TestHandleFileDataThreshold(t *testing.T) {
+	opts := &rollupOptions{dataThreshold: 20, sampleRows: 1, randomRows: 1}
+	if got := handleFile("small.json", handlerSchema, `{"a": 1}`, opts); got != "" {
+		t.Errorf("handleFile() summarized a file under the threshold: %q", got)
+	}
+	if got := handleFile("large.json", handlerSchema, `{"a": 1, "b": [1, 2, 3]}`, opts); !strings.HasPrefix(got, "JSON file") {
+		t.Errorf("handleFile() = %q, want a schema", got)
+	}
+	if got := handleFile("bad.csv", handlerSample, "a,\"b\n1,2\n3,4\n5,6\n7,8\n", opts); got == "" {
+		t.Errorf("handleFile() did not sample a CSV file with a stray quote")
+	}
+}
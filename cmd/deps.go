@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Orders files can be emitted in
+const (
+	orderByPath = "path"
+	orderByDeps = "deps"
+)
+
+// depGraph is the import graph of the Go packages in a rollup. Packages are
+// named by their directory relative to the project root.
+type depGraph struct {
+	// imports maps each package to the packages in the graph it imports
+	imports map[string][]string
+
+	// order lists the packages with every package after its imports
+	order []string
+
+	// cycles lists the packages of each import cycle
+	cycles [][]string
+}
+
+// buildDepGraph finds the packages of the Go files in files and the imports
+// between them within their module. Packages whose files do not parse,
+// such as those in testdata, are left without imports.
+func buildDepGraph(root string, files []*fileSection) *depGraph {
+	packages := make(map[string]bool)
+	for _, f := range files {
+		if strings.HasSuffix(f.Path, ".go") {
+			packages[filepath.ToSlash(filepath.Dir(filepath.FromSlash(f.Path)))] = true
+		}
+	}
+	names := make([]string, 0, len(packages))
+	for name := range packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	g := &depGraph{imports: make(map[string][]string)}
+	modules := make(map[string]*goModule)
+	for _, name := range names {
+		dir := filepath.Join(root, filepath.FromSlash(name))
+		mod, err := findModule(dir)
+		if err != nil {
+			// Packages outside any module have no imports to follow
+			continue
+		}
+		if cached, ok := modules[mod.root]; ok {
+			mod = cached
+		} else {
+			modules[mod.root] = mod
+		}
+
+		imports, err := packageImports(dir, false)
+		if err != nil {
+			fmt.Printf("Warning: ignoring the imports of %s: %v\n", name, err)
+		}
+		for _, importPath := range imports {
+			rest, ok := strings.CutPrefix(importPath, mod.path)
+			if !ok || (rest != "" && rest[0] != '/') {
+				continue
+			}
+			target, err := filepath.Rel(root, filepath.Join(mod.root, filepath.FromSlash(rest)))
+			if err != nil {
+				continue
+			}
+			target = filepath.ToSlash(target)
+			if packages[target] && target != name {
+				g.imports[name] = append(g.imports[name], target)
+			}
+		}
+	}
+
+	g.sort(names)
+	return g
+}
+
+// sort orders the packages with Tarjan's algorithm, which finishes every
+// strongly connected component after the components it imports. Any
+// component of more than one package is an import cycle.
+func (g *depGraph) sort(names []string) {
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+
+	var visit func(name string)
+	visit = func(name string) {
+		index[name] = len(index)
+		low[name] = index[name]
+		stack = append(stack, name)
+		onStack[name] = true
+
+		for _, imported := range g.imports[name] {
+			if _, seen := index[imported]; !seen {
+				visit(imported)
+				low[name] = min(low[name], low[imported])
+			} else if onStack[imported] {
+				low[name] = min(low[name], index[imported])
+			}
+		}
+
+		if low[name] != index[name] {
+			return
+		}
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == name {
+				break
+			}
+		}
+		sort.Strings(component)
+		if len(component) > 1 {
+			g.cycles = append(g.cycles, component)
+		}
+		g.order = append(g.order, component...)
+	}
+
+	for _, name := range names {
+		if _, seen := index[name]; !seen {
+			visit(name)
+		}
+	}
+}
+
+// String renders the graph as a Markdown list in package order
+func (g *depGraph) String() string {
+	var b strings.Builder
+	b.WriteString("Package dependencies, each package after the packages it imports:\n\n")
+	for _, name := range g.order {
+		if imports := g.imports[name]; len(imports) > 0 {
+			fmt.Fprintf(&b, "- `%s` imports `%s`\n", name, strings.Join(imports, "`, `"))
+		} else {
+			fmt.Fprintf(&b, "- `%s`\n", name)
+		}
+	}
+	for _, cycle := range g.cycles {
+		fmt.Fprintf(&b, "\nImport cycle between `%s`\n", strings.Join(cycle, "`, `"))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// orderSections sorts files into package order, keeping the order of files
+// within a package. Files outside the graph's packages follow, in their
+// original order.
+func orderSections(files []*fileSection, g *depGraph) {
+	rank := make(map[string]int)
+	for i, name := range g.order {
+		rank[name] = i
+	}
+	rankOf := func(f *fileSection) int {
+		if r, ok := rank[filepath.ToSlash(filepath.Dir(filepath.FromSlash(f.Path)))]; ok {
+			return r
+		}
+		return len(g.order)
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return rankOf(files[i]) < rankOf(files[j])
+	})
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tnypxl/rollup/internal/config"
+)
+
+func TestBuildDepGraph(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":      "module example.com/app\n",
+		"main.go":     "package main\n\nimport \"example.com/app/a\"\n",
+		"a/a.go":      "package a\n\nimport (\n\t\"strings\"\n\t\"example.com/app/b\"\n\t\"example.com/app/c\"\n)\n",
+		"b/b.go":      "package b\n\nimport \"example.com/app/c\"\n",
+		"c/c.go":      "package c\n",
+		"x/x.go":      "package x\n\nimport \"example.com/app/y\"\n",
+		"y/y.go":      "package y\n\nimport \"example.com/app/x\"\n",
+		"docs/doc.md": "# Docs\n",
+	}
+	writeTree(t, tempDir, files)
+	var sections []*fileSection
+	for name := range files {
+		if name != "go.mod" {
+			sections = append(sections, &fileSection{Path: name})
+		}
+	}
+
+	g := buildDepGraph(tempDir, sections)
+
+	if want := []string{"c", "b", "a", ".", "x", "y"}; !reflect.DeepEqual(g.order, want) {
+		t.Errorf("order = %v, want %v", g.order, want)
+	}
+	if want := [][]string{{"x", "y"}}; !reflect.DeepEqual(g.cycles, want) {
+		t.Errorf("cycles = %v, want %v", g.cycles, want)
+	}
+	if want := []string{"b", "c"}; !reflect.DeepEqual(g.imports["a"], want) {
+		t.Errorf("imports of a = %v, want %v", g.imports["a"], want)
+	}
+	if graph := g.String(); !strings.Contains(graph, "- `a` imports `b`, `c`") || !strings.Contains(graph, "Import cycle between `x`, `y`") {
+		t.Errorf("String() =\n%s", graph)
+	}
+
+	ordered := []*fileSection{{Path: "a/a.go"}, {Path: "b/b.go"}, {Path: "c/c.go"}, {Path: "docs/doc.md"}, {Path: "main.go"}, {Path: "x/x.go"}, {Path: "y/y.go"}}
+	orderSections(ordered, g)
+	var got []string
+	for _, f := range ordered {
+		got = append(got, f.Path)
+	}
+	if want := []string{"c/c.go", "b/b.go", "a/a.go", "main.go", "x/x.go", "y/y.go", "docs/doc.md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("orderSections() = %v, want %v", got, want)
+	}
+}
+
+func TestRunRollupOrderByDepsUnparsable(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTree(t, tempDir, map[string]string{
+		"go.mod":           "module example.com/app\n",
+		"main.go":          "package main\n\nimport \"example.com/app/a\"\n",
+		"a/a.go":           "package a\n",
+		"testdata/bad.go":  "this is not go {{{\n",
+		"testdata/good.go": "package testdata\n",
+	})
+
+	originalWd, _ := os.Getwd()
+	os.Chdir(tempDir)
+	defer os.Chdir(originalWd)
+
+	// A package that does not parse keeps the rest of the order intact
+	cfg = &config.Config{FileExtensions: []string{"go"}, OrderBy: orderByDeps}
+	if err := runRollup(cfg); err != nil {
+		t.Fatalf("runRollup() failed: %v", err)
+	}
+	outputFiles, _ := filepath.Glob("*.rollup.md")
+	if len(outputFiles) != 1 {
+		t.Fatalf("Expected one rollup file, found %v", outputFiles)
+	}
+	data, err := os.ReadFile(outputFiles[0])
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	content := string(data)
+	if a, main := strings.Index(content, "# File: a/a.go"), strings.Index(content, "# File: main.go"); a < 0 || main < a {
+		t.Errorf("a/a.go does not come before main.go:\n%s", content)
+	}
+	if !strings.Contains(content, "# File: testdata/bad.go") {
+		t.Errorf("Output file does not contain testdata/bad.go")
+	}
+}
@@ -0,0 +1,416 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var diffRollupOut string
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <old> <new>",
+	Short: "Compare two rollups file by file",
+	Long: `Diff parses two rollups made by the files subcommand and reports the files added,
+removed and modified between them, with a unified diff for each. Either side may be a
+directory written with --output=separate.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runDiff(args[0], args[1], diffRollupOut)
+	},
+}
+
+func init() {
+	diffCmd.Flags().StringVar(&diffRollupOut, "rollup", "", "Also write the added and modified files as a new rollup to this file")
+}
+
+// Kinds of change between two rollups
+const (
+	changeAdded    = "added"
+	changeRemoved  = "removed"
+	changeModified = "modified"
+)
+
+// fileChange is one file that differs between two rollups
+type fileChange struct {
+	kind     string
+	path     string
+	old, new *unrolledFile
+}
+
+// compareRollups returns the files that differ between two parsed rollups,
+// sorted by path
+func compareRollups(oldFiles, newFiles []*unrolledFile) []fileChange {
+	oldByPath := make(map[string]*unrolledFile)
+	for _, f := range oldFiles {
+		oldByPath[f.Path] = f
+	}
+	newByPath := make(map[string]*unrolledFile)
+	for _, f := range newFiles {
+		newByPath[f.Path] = f
+	}
+
+	var changes []fileChange
+	for _, f := range newFiles {
+		old, ok := oldByPath[f.Path]
+		switch {
+		case !ok:
+			changes = append(changes, fileChange{changeAdded, f.Path, nil, f})
+		case old.Content != f.Content || old.SymlinkTarget != f.SymlinkTarget:
+			changes = append(changes, fileChange{changeModified, f.Path, old, f})
+		}
+	}
+	for _, f := range oldFiles {
+		if _, ok := newByPath[f.Path]; !ok {
+			changes = append(changes, fileChange{changeRemoved, f.Path, f, nil})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].path < changes[j].path })
+	return changes
+}
+
+// diffText is what a file is compared as: its content, or a line naming
+// the target of a listed symlink
+func diffText(f *unrolledFile) string {
+	if f == nil {
+		return ""
+	}
+	if f.SymlinkTarget != "" {
+		return "Symlink to " + f.SymlinkTarget + "\n"
+	}
+	return f.Content
+}
+
+// patch renders the unified diff of one change
+func (c fileChange) patch() string {
+	oldName, newName := "a/"+c.path, "b/"+c.path
+	switch c.kind {
+	case changeAdded:
+		oldName = "/dev/null"
+	case changeRemoved:
+		newName = "/dev/null"
+	}
+	return unifiedDiff(oldName, newName, diffText(c.old), diffText(c.new))
+}
+
+func runDiff(oldSource, newSource, rollupOut string) error {
+	oldFiles, err := readRollup(oldSource)
+	if err != nil {
+		return err
+	}
+	newFiles, err := readRollup(newSource)
+	if err != nil {
+		return err
+	}
+
+	changes := compareRollups(oldFiles, newFiles)
+	counts := make(map[string]int)
+	for _, c := range changes {
+		counts[c.kind]++
+		fmt.Printf("%-9s %s\n", c.kind, c.path)
+	}
+	fmt.Printf("%d added, %d removed, %d modified, %d unchanged\n",
+		counts[changeAdded], counts[changeRemoved], counts[changeModified],
+		len(newFiles)-counts[changeAdded]-counts[changeModified])
+
+	for _, c := range changes {
+		fmt.Println()
+		fmt.Print(c.patch())
+	}
+
+	if rollupOut != "" {
+		if err := writeChangesRollup(rollupOut, oldSource, newSource, changes); err != nil {
+			return err
+		}
+		fmt.Printf("\nChanged files written to %s\n", rollupOut)
+	}
+	return nil
+}
+
+// writeChangesRollup writes the added and modified files of changes as a
+// rollup in the default format, listing removed files in its preamble
+func writeChangesRollup(outputPath, oldSource, newSource string, changes []fileChange) error {
+	tmpl, err := loadTemplate("")
+	if err != nil {
+		return err
+	}
+
+	project := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(newSource), ".md"), ".rollup")
+	stats := newRollupStats()
+	data := &templateData{
+		Project:   project,
+		Generated: time.Now(),
+		Stats:     stats,
+	}
+
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "Files changed from %s to %s.", filepath.Base(oldSource), filepath.Base(newSource))
+	var paths []string
+	for _, c := range changes {
+		if c.kind == changeRemoved {
+			fmt.Fprintf(&summary, "\n\nRemoved: %s", c.path)
+			continue
+		}
+		f := c.new
+		entry := &fileEntry{relPath: filepath.FromSlash(f.Path), codeGen: f.CodeGenerated, linkTarget: f.SymlinkTarget}
+		section := &fileSection{
+			Path:          f.Path,
+			Lang:          languageFor(f.Path, cfg.LanguageMap),
+			Content:       f.Content,
+			Source:        f.Content,
+			CodeGenerated: f.CodeGenerated,
+			SymlinkTarget: f.SymlinkTarget,
+			Size:          int64(len(f.Content)),
+			Lines:         countLines(f.Content),
+			Tokens:        estimateTokens(f.Content),
+			entry:         entry,
+		}
+		if f.Summary {
+			section.Summary = f.Content
+		}
+		if f.Notebook {
+			section.Notebook, section.converted = true, f.Content
+		}
+		stats.addIncluded(entry, f.Content)
+		data.Files = append(data.Files, section)
+		paths = append(paths, f.Path)
+	}
+	stats.finish()
+	data.Tree = renderTree(project, paths)
+	data.Preamble = summary.String()
+
+	outputFile, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error creating output file: %v", err)
+	}
+	defer outputFile.Close()
+	return renderTemplate(outputFile, tmpl, data)
+}
+
+// diffContext is the number of unchanged lines shown around each change in
+// a unified diff
+const diffContext = 3
+
+// diffOp is one line of an edit script: kept (' '), removed ('-') or
+// added ('+')
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// splitLines splits content into lines, each keeping its newline
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the shortest edit script turning a into b, using
+// Myers' algorithm
+func diffLines(a, b []string) []diffOp {
+	ops := myers(nil, a, b)
+
+	// Show each run of changes as removals followed by additions, as diff
+	// tools do
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+		end := start
+		for end < len(ops) && ops[end].kind != ' ' {
+			end++
+		}
+		sort.SliceStable(ops[start:end], func(i, j int) bool {
+			return ops[start+i].kind == '-' && ops[start+j].kind == '+'
+		})
+		start = end
+	}
+	return ops
+}
+
+// myers appends to ops the edit script turning a into b. It is the linear
+// space variant of Myers' algorithm: it finds the snake in the middle of
+// an optimal edit path and recurses on either side of it, so memory stays
+// proportional to the input rather than to its square.
+func myers(ops []diffOp, a, b []string) []diffOp {
+	// Common prefixes and suffixes need no search and usually make up most
+	// of an edited file
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	a, b = a[prefix:], b[prefix:]
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	common := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	switch {
+	case len(a) == 0:
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+	case len(b) == 0:
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+	default:
+		// Both halves have a shorter edit path than the whole, since a
+		// and b now differ in their first and last lines
+		x, y, u, v := middleSnake(a, b)
+		ops = myers(ops, a[:x], b[:y])
+		for _, line := range a[x:u] {
+			ops = append(ops, diffOp{' ', line})
+		}
+		ops = myers(ops, a[u:], b[v:])
+	}
+
+	for _, line := range common {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// middleSnake returns the start (x, y) and end (u, v) of the snake in the
+// middle of an optimal edit path from a to b. It searches forward from the
+// start and backward from the end at once, keeping the furthest point
+// reached on each diagonal, until the two searches meet.
+func middleSnake(a, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	limit := (n + m + 1) / 2
+	offset := limit + 1
+
+	// forward holds the furthest x of the forward search on each diagonal
+	// k = x - y, and backward the smallest x of the backward search on
+	// each diagonal c = x - y - delta
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+	backward[offset+1] = n + 1
+
+	for d := 0; d <= limit; d++ {
+		for k := -d; k <= d; k += 2 {
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y = x - k
+			u, v = x, y
+			for u < n && v < m && a[u] == b[v] {
+				u++
+				v++
+			}
+			forward[offset+k] = u
+			if c := k - delta; odd && c >= -(d-1) && c <= d-1 && u >= backward[offset+c] {
+				return x, y, u, v
+			}
+		}
+
+		for c := -d; c <= d; c += 2 {
+			if c == -d || (c != d && backward[offset+c+1]-1 < backward[offset+c-1]) {
+				u = backward[offset+c+1] - 1
+			} else {
+				u = backward[offset+c-1]
+			}
+			v = u - c - delta
+			x, y = u, v
+			for x > 0 && y > 0 && a[x-1] == b[y-1] {
+				x--
+				y--
+			}
+			backward[offset+c] = x
+			if k := c + delta; !odd && k >= -d && k <= d && forward[offset+k] >= x {
+				return x, y, u, v
+			}
+		}
+	}
+	// The searches meet by the time each has covered half the edit path
+	panic("diff: searches did not meet")
+}
+
+// unifiedDiff renders the changes from oldContent to newContent as a
+// unified diff, or returns "" when they are equal
+func unifiedDiff(oldName, newName, oldContent, newContent string) string {
+	if oldContent == newContent {
+		return ""
+	}
+	ops := diffLines(splitLines(oldContent), splitLines(newContent))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+
+	// Line numbers in the old and new file at the start of each op
+	oldLine := make([]int, len(ops)+1)
+	newLine := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldLine[i+1], newLine[i+1] = oldLine[i], newLine[i]
+		if op.kind != '+' {
+			oldLine[i+1]++
+		}
+		if op.kind != '-' {
+			newLine[i+1]++
+		}
+	}
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// Extend the hunk while the next change is close enough to share
+		// context
+		start := max(i-diffContext, 0)
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			} else if j-end >= 2*diffContext {
+				break
+			}
+		}
+		end = min(end+diffContext, len(ops))
+
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n",
+			hunkRange(oldLine[start], oldLine[end]-oldLine[start]),
+			hunkRange(newLine[start], newLine[end]-newLine[start]))
+		for _, op := range ops[start:end] {
+			b.WriteByte(op.kind)
+			b.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return b.String()
+}
+
+// hunkRange formats the start and length of one side of a hunk header
+func hunkRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
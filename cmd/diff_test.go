@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tnypxl/rollup/internal/config"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{
+			name: "equal",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			want: "",
+		},
+		{
+			name: "new file",
+			old:  "",
+			new:  "a\nb\n",
+			want: "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "changed line",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			new:  "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			want: "--- old\n+++ new\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name: "separate hunks",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			new:  "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\ntwelve\n",
+			want: "--- old\n+++ new\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -10,3 +10,4 @@\n 10\n 11\n 12\n+twelve\n",
+		},
+		{
+			name: "missing final newline",
+			old:  "a\nb",
+			new:  "a\nb\n",
+			want: "--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unifiedDiff("old", "new", tt.old, tt.new); got != tt.want {
+				t.Errorf("unifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareRollups(t *testing.T) {
+	oldFiles := []*unrolledFile{
+		{Path: "b.go", Content: "package b\n"},
+		{Path: "gone.go", Content: "package gone\n"},
+		{Path: "a.go", Content: "package a\n"},
+		{Path: "link.go", SymlinkTarget: "a.go"},
+	}
+	newFiles := []*unrolledFile{
+		{Path: "a.go", Content: "package a\n"},
+		{Path: "b.go", Content: "package b // edited\n"},
+		{Path: "c.go", Content: "package c\n"},
+		{Path: "link.go", SymlinkTarget: "c.go"},
+	}
+
+	var got []string
+	for _, c := range compareRollups(oldFiles, newFiles) {
+		got = append(got, c.kind+" "+c.path)
+	}
+	want := []string{"modified b.go", "added c.go", "removed gone.go", "modified link.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("compareRollups() = %v, want %v", got, want)
+	}
+}
+
+func TestRunDiffRollup(t *testing.T) {
+	tempDir := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(tempDir, name)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return p
+	}
+	oldRollup := write("old.rollup.md", "# File: a.go\n\n```go\npackage a\n```\n\n# File: gone.go\n\n```go\npackage gone\n```\n")
+	newRollup := write("new.rollup.md", "# File: a.go\n\n```go\npackage a // edited\n```\n\n# File: b.go\n\n```go\npackage b\n```\n")
+	out := filepath.Join(tempDir, "changes.rollup.md")
+
+	cfg = &config.Config{}
+
+	if err := runDiff(oldRollup, newRollup, out); err != nil {
+		t.Fatalf("runDiff() returned error: %v", err)
+	}
+
+	content, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("Failed to read changes rollup: %v", err)
+	}
+	if !strings.Contains(string(content), "Removed: gone.go") {
+		t.Errorf("changes rollup does not list the removed file:\n%s", content)
+	}
+	files, err := parseRollup(string(content))
+	if err != nil {
+		t.Fatalf("changes rollup does not parse: %v", err)
+	}
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	if want := []string{"a.go", "b.go"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("changes rollup holds %v, want %v", paths, want)
+	}
+}
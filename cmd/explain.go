@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var explainCmd = &cobra.Command{
+	Use:   "explain <path>",
+	Short: "Explain why a file is or is not included in a rollup",
+	Long: `Explain applies the same rules as the files subcommand to a single path and prints
+the decision together with the config setting or pattern that made it.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runExplain(args[0])
+	},
+}
+
+func init() {
+	addSelectionFlags(explainCmd)
+}
+
+func runExplain(target string) error {
+	opts, err := resolveOptions(cfg)
+	if err != nil {
+		return err
+	}
+
+	entry, err := newSelector(opts, "").explain(target)
+	if err != nil {
+		return err
+	}
+
+	decision := "included"
+	if !entry.included() {
+		decision = "excluded (" + entry.reason + ")"
+	}
+	fmt.Printf("Path:     %s\n", filepath.ToSlash(entry.relPath))
+	fmt.Printf("Decision: %s\n", decision)
+	if entry.rule != "" {
+		fmt.Printf("Rule:     %s\n", entry.rule)
+	}
+	if entry.codeGen {
+		fmt.Printf("Code-generated: yes, by %s\n", entry.codeGenRule)
+	}
+	if entry.linkTarget != "" {
+		fmt.Printf("Symlink:  %s\n", entry.linkTarget)
+	}
+	return nil
+}
+
+// explain decides a single path the way a walk of the project would. The
+// path may be given relative to the working directory or to the project.
+func (s *selector) explain(target string) (*fileEntry, error) {
+	filePath := target
+	if !filepath.IsAbs(filePath) {
+		if _, err := os.Lstat(filePath); err != nil {
+			filePath = filepath.Join(s.opts.root, filePath)
+		}
+	}
+	filePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error getting absolute path: %v", err)
+	}
+	relPath, err := filepath.Rel(s.opts.root, filePath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("%s is outside the project directory %s", target, s.opts.root)
+	}
+
+	info, err := os.Lstat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", target, err)
+	}
+	// The walk only enters linked directories when it follows links
+	if link := linkedAncestor(s.opts.root, relPath); link != "" && s.opts.symlinks != symlinksFollow {
+		rule := fmt.Sprintf("inside %s, a symlink skipped with symlinks: skip", link)
+		if s.opts.symlinks == symlinksList {
+			rule = fmt.Sprintf("inside %s, a symlinked directory listed with symlinks: list", link)
+		}
+		return &fileEntry{path: filePath, relPath: relPath, info: info, reason: reasonSymlink, rule: rule}, nil
+	}
+	if isSymlink(info) {
+		switch s.opts.symlinks {
+		case symlinksSkip:
+			return &fileEntry{path: filePath, relPath: relPath, info: info, reason: reasonSymlink, rule: "symlinks: skip"}, nil
+		case symlinksFollow:
+			if info, err = os.Stat(filePath); err != nil {
+				return &fileEntry{path: filePath, relPath: relPath, reason: reasonMissing, rule: "broken symlink"}, nil
+			}
+		}
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory; explain decides files", target)
+	}
+
+	if isHiddenPath(relPath) && !allowHidden(relPath, false, s.opts.includeHidden, s.include) {
+		return &fileEntry{path: filePath, relPath: relPath, info: info, reason: reasonHidden, rule: s.hiddenRule()}, nil
+	}
+	return s.decide(filePath, relPath, info, true), nil
+}
+
+// linkedAncestor returns the first directory between root and the file at
+// relPath that is a symlink, relative to root, or "" when there is none
+func linkedAncestor(root, relPath string) string {
+	parts := strings.Split(filepath.ToSlash(relPath), "/")
+	for i := 1; i < len(parts); i++ {
+		dir := strings.Join(parts[:i], "/")
+		if info, err := os.Lstat(filepath.Join(root, filepath.FromSlash(dir))); err == nil && isSymlink(info) {
+			return dir
+		}
+	}
+	return ""
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package cmd
+
+import "os"
+
+// fileKey identifies a file by its fully resolved path on platforms without
+// device and inode numbers.
+func fileKey(path string, info os.FileInfo) string {
+	return resolvedPath(path)
+}
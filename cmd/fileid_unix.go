@@ -0,0 +1,18 @@
+//go:build unix
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// fileKey identifies a file by device and inode, so the same directory is
+// recognised no matter which link it was reached through.
+func fileKey(path string, info os.FileInfo) string {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return fmt.Sprintf("%d:%d", uint64(st.Dev), uint64(st.Ino))
+	}
+	return resolvedPath(path)
+}
@@ -1,10 +1,14 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -18,8 +22,43 @@ var (
 	fileTypes       string
 	codeGenPatterns string
 	ignorePatterns  string
+	fromStdin       bool
+	filesFrom       string
+	noFilter        bool
+	symlinks        string
+	includeHidden   bool
+	includePatterns string
+	caseInsensitive bool
+	lineNumbers     bool
+	metadata        string
+	statsJSON       string
+	maxFileSize     string
+	dryRun          bool
+	templatePath    string
+	prompt          string
+	splitDepth      int
+	chunksPath      string
+	chunkBy         string
+	chunkSize       int
+	chunkOverlap    int
+	focusPaths      string
+	focusDepth      int
+	focusTests      bool
+	orderBy         string
+	symbolIndexFlag bool
+	roles           string
+	testsPlacement  string
+	modulePaths     string
+	rawManifests    bool
+	notebookOutputs bool
+	dataThreshold   string
+	sampleRows      int
+	randomRows      int
 )
 
+// stdin is read by --from-stdin; tests replace it with a fixed reader
+var stdin io.Reader = os.Stdin
+
 var filesCmd = &cobra.Command{
 	Use:   "files",
 	Short: "Rollup files into a single Markdown file",
@@ -32,200 +71,610 @@ whose name is <project-directory-name>-rollup-<timestamp>.md.`,
 }
 
 func init() {
-	filesCmd.Flags().StringVarP(&path, "path", "p", ".", "Path to the project directory")
-	filesCmd.Flags().StringVarP(&fileTypes, "types", "t", "go,md,txt", "Comma-separated list of file extensions to include (without leading dot)")
-	filesCmd.Flags().StringVarP(&codeGenPatterns, "codegen", "g", "", "Comma-separated list of glob patterns for code-generated files")
-	filesCmd.Flags().StringVarP(&ignorePatterns, "ignore", "i", "", "Comma-separated list of glob patterns for files to ignore")
+	addSelectionFlags(filesCmd)
+	filesCmd.Flags().BoolVar(&fromStdin, "from-stdin", false, "Read the list of files to roll up from stdin, one path per line")
+	filesCmd.Flags().StringVar(&filesFrom, "files-from", "", "Read the list of files to roll up from the given file, one path per line")
+	filesCmd.Flags().BoolVar(&noFilter, "no-filter", false, "Include every listed file, skipping the ignore and file type rules")
+	filesCmd.Flags().BoolVar(&lineNumbers, "line-numbers", false, "Prefix each line in code blocks with its line number")
+	filesCmd.Flags().StringVar(&metadata, "metadata", "", "Comma-separated list of per-file metadata to show: size, lines, tokens, sha256, mtime, git")
+	filesCmd.Flags().StringVarP(&outputType, "output", "o", "", "Output type: 'single' for one file, 'separate' for one file per directory plus an index")
+	filesCmd.Flags().IntVar(&splitDepth, "split-depth", 1, "Directory levels used to group files with --output=separate; 0 groups by package directory")
+	filesCmd.Flags().StringVar(&orderBy, "order-by", orderByPath, "Order of files: 'path', or 'deps' to put Go packages after the packages they import")
+	filesCmd.Flags().BoolVar(&symbolIndexFlag, "symbols", false, "Start the rollup with an index of exported Go types, functions and methods")
+	filesCmd.Flags().BoolVar(&rawManifests, "raw-manifests", false, "Include manifests and lockfiles as they are instead of as dependency tables and summaries")
+	filesCmd.Flags().BoolVar(&notebookOutputs, "notebook-outputs", false, "Include the outputs of Jupyter notebook code cells")
+	filesCmd.Flags().StringVar(&dataThreshold, "data-threshold", defaultDataThreshold, "Sample CSV and TSV files and summarize JSON and YAML files from this size up")
+	filesCmd.Flags().IntVar(&sampleRows, "sample-rows", defaultSampleRows, "Leading rows shown of sampled CSV and TSV files")
+	filesCmd.Flags().IntVar(&randomRows, "random-rows", defaultRandomRows, "Rows picked at random from the rest of sampled CSV and TSV files")
+	filesCmd.Flags().StringVar(&templatePath, "template", "", "Path to a text/template file used to render the rollup")
+	filesCmd.Flags().StringVar(&prompt, "prompt", "", "Question appended to the end of the rollup, after the postamble")
+	filesCmd.Flags().StringVar(&chunksPath, "chunks", "", "Also write each included file as overlapping chunks to the given JSONL file")
+	filesCmd.Flags().StringVar(&chunkBy, "chunk-by", chunkByLines, "Unit of chunk sizes: 'lines' or 'tokens'")
+	filesCmd.Flags().IntVar(&chunkSize, "chunk-size", 0, "Largest chunk in lines or tokens (default 100 lines or 512 tokens)")
+	filesCmd.Flags().IntVar(&chunkOverlap, "chunk-overlap", -1, "Lines or tokens repeated at the start of the next chunk; -1 uses a tenth of the chunk size")
+	filesCmd.Flags().StringVar(&statsJSON, "stats-json", "", "Write the run statistics as JSON to the given file")
+	filesCmd.Flags().BoolVar(&dryRun, "dry-run", false, "List every candidate file and the decision made about it without writing a rollup")
+	filesCmd.MarkFlagsMutuallyExclusive("from-stdin", "files-from")
 }
 
-func matchGlob(pattern, path string) bool {
-	parts := strings.Split(pattern, "/")
-	return matchGlobRecursive(parts, path)
+// addSelectionFlags registers the flags that decide which files are rolled
+// up, shared by the files and explain commands
+func addSelectionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&path, "path", "p", ".", "Path to the project directory")
+	cmd.Flags().StringVarP(&fileTypes, "types", "t", "go,md,txt", "Comma-separated list of file extensions to include (without leading dot)")
+	cmd.Flags().StringVarP(&codeGenPatterns, "codegen", "g", "", "Comma-separated list of glob patterns for code-generated files")
+	cmd.Flags().StringVarP(&ignorePatterns, "ignore", "i", "", "Comma-separated list of glob patterns for files to ignore")
+	cmd.Flags().StringVar(&symlinks, "symlinks", symlinksSkip, "How to treat symlinks: 'skip', 'follow' or 'list'")
+	cmd.Flags().BoolVar(&includeHidden, "hidden", false, "Include hidden files and directories (.git is still skipped)")
+	cmd.Flags().StringVar(&includePatterns, "include", "", "Comma-separated list of glob patterns for hidden paths to include")
+	cmd.Flags().BoolVar(&caseInsensitive, "ignore-case", false, "Match ignore, include and code-generated patterns case-insensitively")
+	cmd.Flags().StringVar(&maxFileSize, "max-size", "", "Skip files larger than this size, e.g. 512KB or 2MB")
+	cmd.Flags().StringVar(&roles, "roles", "", "Comma-separated roles of files to include: source, test, docs, config, build, fixture")
+	cmd.Flags().StringVar(&testsPlacement, "tests", "", "Where test files go: 'adjacent' after their source file, 'end' after all other files, or 'omit'")
+	cmd.Flags().StringVar(&modulePaths, "module", "", "Comma-separated Go module paths to include; a path ending in /... also selects the modules below it")
+	cmd.Flags().StringVar(&focusPaths, "focus", "", "Comma-separated Go files or package directories; include only the packages they import within their module")
+	cmd.Flags().IntVar(&focusDepth, "focus-depth", 0, "Follow at most this many imports from --focus (0 for no limit)")
+	cmd.Flags().BoolVar(&focusTests, "focus-tests", false, "Include the _test.go files of focused packages and follow their imports")
 }
 
-func matchGlobRecursive(patternParts []string, path string) bool {
-	if len(patternParts) == 0 {
-		return path == ""
-	}
-
-	if patternParts[0] == "**" {
-		for i := 0; i <= len(path); i++ {
-			if matchGlobRecursive(patternParts[1:], path[i:]) {
-				return true
-			}
+// isHiddenPath reports whether any element of filePath starts with a dot
+func isHiddenPath(filePath string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(filePath), "/") {
+		if strings.HasPrefix(part, ".") && part != "." && part != ".." {
+			return true
 		}
-		return false
-	}
-
-	i := strings.IndexByte(path, '/')
-	if i < 0 {
-		matched, _ := filepath.Match(patternParts[0], path)
-		return matched && len(patternParts) == 1
 	}
-
-	matched, _ := filepath.Match(patternParts[0], path[:i])
-	return matched && matchGlobRecursive(patternParts[1:], path[i+1:])
+	return false
 }
 
-func isCodeGenerated(filePath string, patterns []string) bool {
-	for _, pattern := range patterns {
-		if strings.Contains(pattern, "**") {
-			if matchGlob(pattern, filePath) {
-				return true
-			}
-		} else {
-			matched, err := filepath.Match(pattern, filepath.Base(filePath))
-			if err == nil && matched {
-				return true
-			}
+// isGitPath reports whether filePath is inside a .git directory
+func isGitPath(filePath string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(filePath), "/") {
+		if part == ".git" {
+			return true
 		}
 	}
 	return false
 }
 
-func isIgnored(filePath string, patterns []string) bool {
-	for _, pattern := range patterns {
-		if strings.Contains(pattern, "**") {
-			if matchGlob(pattern, filePath) {
-				return true
-			}
-		} else {
-			// Check if the pattern matches the full path or any part of it
-			if matched, _ := filepath.Match(pattern, filePath); matched {
-				return true
-			}
-			pathParts := strings.Split(filePath, string(os.PathSeparator))
-			for i := range pathParts {
-				partialPath := filepath.Join(pathParts[:i+1]...)
-				if matched, _ := filepath.Match(pattern, partialPath); matched {
-					return true
-				}
-			}
-		}
+// allowHidden reports whether a hidden path may be rolled up. Hidden paths
+// are allowed when include_hidden is set, except for .git, or when they
+// match an include pattern.
+func allowHidden(relPath string, isDir, includeHidden bool, include *globMatcher) bool {
+	if includeHidden && !isGitPath(relPath) {
+		return true
 	}
-	return false
+	return include.Match(relPath, isDir)
 }
 
-func runRollup(cfg *config.Config) error {
+// rollupOptions holds the settings for a file rollup, resolved from the
+// config file and the command-line flags
+type rollupOptions struct {
+	root            string
+	types           []string
+	ignore          []string
+	codeGen         []string
+	include         []string
+	maxFileSize     int64
+	includeHidden   bool
+	foldCase        bool
+	symlinks        string
+	metadata        []string
+	lineNumbers     bool
+	languageMap     map[string]string
+	handlers        map[string]string
+	notebookOutputs bool
+	dataThreshold   int64
+	sampleRows      int
+	randomRows      int
+	template        string
+	preamble        string
+	postamble       string
+	outputType      string
+	splitDepth      int
+	orderBy         string
+	symbolIndex     bool
+	roles           []string
+	rolesSource     string
+	tests           string
+	modules         []string
+	modulesSource   string
+	chunkBy         string
+	chunkSize       int
+	chunkOverlap    int
+
+	// focus limits a rollup to the packages reachable from --focus, or is
+	// nil to include every package
+	focus *focusSet
+
+	// fileList holds the explicit list of files to roll up, or nil to walk
+	// the project
+	fileList   []string
+	filterList bool
+
+	// The settings the rules came from, for explaining decisions
+	typesSource   string
+	ignoreSource  string
+	codeGenSource string
+	maxSizeSource string
+}
+
+// resolveOptions merges cfg with the command-line flags. Settings present
+// in the config file take precedence.
+func resolveOptions(cfg *config.Config) (*rollupOptions, error) {
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	opts := &rollupOptions{
+		types:           strings.Split(fileTypes, ","),
+		codeGen:         strings.Split(codeGenPatterns, ","),
+		ignore:          strings.Split(ignorePatterns, ","),
+		includeHidden:   includeHidden || cfg.IncludeHidden,
+		foldCase:        caseInsensitive || cfg.CaseInsensitive,
+		symlinks:        symlinks,
+		lineNumbers:     lineNumbers || cfg.LineNumbers,
+		languageMap:     cfg.LanguageMap,
+		template:        templatePath,
+		preamble:        cfg.Preamble,
+		postamble:       cfg.Postamble,
+		outputType:      outputType,
+		splitDepth:      splitDepth,
+		orderBy:         orderBy,
+		symbolIndex:     symbolIndexFlag || cfg.SymbolIndex,
+		notebookOutputs: notebookOutputs || cfg.NotebookOutputs,
+		sampleRows:      sampleRows,
+		randomRows:      randomRows,
+		rolesSource:     "--roles",
+		modulesSource:   "--module",
+		tests:           testsPlacement,
+		chunkBy:         chunkBy,
+		chunkSize:       chunkSize,
+		chunkOverlap:    chunkOverlap,
+		filterList:      !noFilter,
+		typesSource:     "--types",
+		ignoreSource:    "--ignore",
+		codeGenSource:   "--codegen",
+		maxSizeSource:   "--max-size",
+	}
+
 	// Use config if available, otherwise use command-line flags
-	var types []string
-	var codeGenList, ignoreList []string
-	if cfg != nil && len(cfg.FileExtensions) > 0 {
-		types = cfg.FileExtensions
-	} else {
-		types = strings.Split(fileTypes, ",")
+	if len(cfg.FileExtensions) > 0 {
+		opts.types, opts.typesSource = cfg.FileExtensions, "file_extensions"
 	}
-	if cfg != nil && len(cfg.CodeGeneratedPaths) > 0 {
-		codeGenList = cfg.CodeGeneratedPaths
-	} else {
-		codeGenList = strings.Split(codeGenPatterns, ",")
+	if len(cfg.CodeGeneratedPaths) > 0 {
+		opts.codeGen, opts.codeGenSource = cfg.CodeGeneratedPaths, "code_generated_paths"
 	}
-	if cfg != nil && len(cfg.IgnorePaths) > 0 {
-		ignoreList = cfg.IgnorePaths
-	} else {
-		ignoreList = strings.Split(ignorePatterns, ",")
+	if len(cfg.IgnorePaths) > 0 {
+		opts.ignore, opts.ignoreSource = cfg.IgnorePaths, "ignore_paths"
+	}
+	maxSize := maxFileSize
+	if cfg.MaxFileSize != "" {
+		maxSize, opts.maxSizeSource = cfg.MaxFileSize, "max_file_size"
+	}
+	size, err := config.ParseSize(maxSize)
+	if err != nil {
+		return nil, fmt.Errorf("invalid maximum file size: %v", err)
+	}
+	opts.maxFileSize = size
+	if len(cfg.IncludePaths) > 0 {
+		opts.include = cfg.IncludePaths
+	} else if includePatterns != "" {
+		opts.include = strings.Split(includePatterns, ",")
+	}
+	if len(cfg.FileMetadata) > 0 {
+		opts.metadata = cfg.FileMetadata
+	} else if metadata != "" {
+		opts.metadata = strings.Split(metadata, ",")
+	}
+	if err := validateMetadataFields(opts.metadata); err != nil {
+		return nil, err
+	}
+	if cfg.Symlinks != "" {
+		opts.symlinks = cfg.Symlinks
+	}
+	if cfg.Template != "" {
+		opts.template = cfg.Template
+	}
+	if cfg.OutputType != "" {
+		opts.outputType = cfg.OutputType
+	}
+	switch opts.outputType {
+	case "", outputSingle, outputSeparate:
+	default:
+		return nil, fmt.Errorf("invalid output type %q: must be 'single' or 'separate'", opts.outputType)
+	}
+	if cfg.SplitDepth != nil {
+		opts.splitDepth = *cfg.SplitDepth
+	}
+	if opts.splitDepth < 0 {
+		return nil, fmt.Errorf("split depth must not be negative")
+	}
+	if len(cfg.Roles) > 0 {
+		opts.roles, opts.rolesSource = cfg.Roles, "roles"
+	} else if roles != "" {
+		opts.roles = strings.Split(roles, ",")
+	}
+	if err := validateRoles(opts.roles); err != nil {
+		return nil, err
+	}
+	if cfg.Tests != "" {
+		opts.tests = cfg.Tests
+	}
+	switch opts.tests {
+	case "", testsAdjacent, testsEnd, testsOmit:
+	default:
+		return nil, fmt.Errorf("invalid test placement %q: must be 'adjacent', 'end' or 'omit'", opts.tests)
+	}
+	if cfg.OrderBy != "" {
+		opts.orderBy = cfg.OrderBy
+	}
+	if opts.orderBy != orderByPath && opts.orderBy != orderByDeps {
+		return nil, fmt.Errorf("invalid order %q: must be 'path' or 'deps'", opts.orderBy)
+	}
+	if opts.handlers, err = resolveHandlers(cfg.FileHandlers, rawManifests); err != nil {
+		return nil, err
+	}
+	threshold := dataThreshold
+	if cfg.DataThreshold != "" {
+		threshold = cfg.DataThreshold
+	}
+	if opts.dataThreshold, err = config.ParseSize(threshold); err != nil {
+		return nil, fmt.Errorf("invalid data threshold: %v", err)
+	}
+	if cfg.SampleRows != nil {
+		opts.sampleRows = *cfg.SampleRows
+	}
+	if cfg.RandomRows != nil {
+		opts.randomRows = *cfg.RandomRows
+	}
+	if opts.sampleRows < 0 || opts.randomRows < 0 {
+		return nil, fmt.Errorf("sampled rows must not be negative")
+	}
+	if cfg.ChunkBy != "" {
+		opts.chunkBy = cfg.ChunkBy
+	}
+	if _, ok := defaultChunkSize[opts.chunkBy]; !ok {
+		return nil, fmt.Errorf("invalid chunk unit %q: must be 'lines' or 'tokens'", opts.chunkBy)
+	}
+	if cfg.ChunkSize != 0 {
+		opts.chunkSize = cfg.ChunkSize
+	}
+	if opts.chunkSize <= 0 {
+		opts.chunkSize = defaultChunkSize[opts.chunkBy]
+	}
+	if cfg.ChunkOverlap != nil {
+		opts.chunkOverlap = *cfg.ChunkOverlap
+	}
+	if opts.chunkOverlap < 0 {
+		opts.chunkOverlap = opts.chunkSize / 10
+	}
+	if opts.chunkOverlap >= opts.chunkSize {
+		return nil, fmt.Errorf("chunk overlap must be smaller than the chunk size")
+	}
+	switch opts.symlinks {
+	case symlinksSkip, symlinksFollow, symlinksList:
+	default:
+		return nil, fmt.Errorf("invalid symlink policy %q: must be 'skip', 'follow' or 'list'", opts.symlinks)
 	}
 
 	// Get the absolute path
 	absPath, err := filepath.Abs(path)
 	if err != nil {
-		return fmt.Errorf("error getting absolute path: %v", err)
+		return nil, fmt.Errorf("error getting absolute path: %v", err)
+	}
+	opts.root = absPath
+
+	if len(cfg.Modules) > 0 {
+		opts.modules, opts.modulesSource = cfg.Modules, "modules"
+	} else if modulePaths != "" {
+		opts.modules = strings.Split(modulePaths, ",")
+	}
+	if err := validateModuleFilter(opts.root, opts.modules); err != nil {
+		return nil, err
+	}
+
+	focus := focusPaths
+	if len(cfg.Focus) > 0 {
+		focus = strings.Join(cfg.Focus, ",")
+	}
+	if focus != "" {
+		depth := focusDepth
+		if cfg.FocusDepth != 0 {
+			depth = cfg.FocusDepth
+		}
+		opts.focus, err = resolveFocus(opts.root, strings.Split(focus, ","), depth, focusTests || cfg.FocusTests)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Read the explicit file list before creating any output
+	if fromStdin || filesFrom != "" {
+		opts.fileList, err = readFileList()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return opts, nil
+}
+
+func runRollup(cfg *config.Config) error {
+	opts, err := resolveOptions(cfg)
+	if err != nil {
+		return err
 	}
 
 	// Get the project directory name
-	projectName := filepath.Base(absPath)
+	projectName := filepath.Base(opts.root)
 
 	// Generate the output file name
 	timestamp := time.Now().Format("20060102-150405")
 	outputFileName := fmt.Sprintf("%s-%s.rollup.md", projectName, timestamp)
+	outputPath, _ := filepath.Abs(outputFileName)
 
-	// Open the output file
-	outputFile, err := os.Create(outputFileName)
+	progress := newProgressReporter()
+	defer progress.stop()
+
+	entries, err := newSelector(opts, outputPath).selectFiles()
+	if err != nil {
+		return err
+	}
+	if err := checkModuleFilter(opts.root, opts.modules, entries); err != nil {
+		return err
+	}
+
+	if dryRun {
+		for _, entry := range entries {
+			fmt.Println(entry.describe())
+		}
+		return nil
+	}
+
+	tmpl, err := loadTemplate(opts.template)
 	if err != nil {
-		return fmt.Errorf("error creating output file: %v", err)
+		return err
 	}
-	defer outputFile.Close()
 
-	startTime := time.Now()
-	showProgress := false
-	progressTicker := time.NewTicker(500 * time.Millisecond)
-	defer progressTicker.Stop()
+	stats := newRollupStats()
+	data := &templateData{
+		Project:     projectName,
+		Root:        opts.root,
+		Generated:   time.Now(),
+		LineNumbers: opts.lineNumbers,
+		Stats:       stats,
+	}
+	for _, entry := range entries {
+		if entry.info != nil && entry.info.IsDir() {
+			continue
+		}
+		if !entry.included() {
+			stats.addSkipped(entry.reason)
+			continue
+		}
+		section, err := loadSection(entry, opts)
+		if err != nil {
+			fmt.Printf("Error reading file %s: %v\n", entry.path, err)
+			stats.addSkipped(reasonMissing)
+			continue
+		}
+		stats.addIncluded(entry, section.text())
+		data.Files = append(data.Files, section)
+		progress.tick()
+	}
+	stats.finish()
+
+	if opts.orderBy == orderByDeps {
+		graph := buildDepGraph(opts.root, data.Files)
+		for _, cycle := range graph.cycles {
+			fmt.Printf("Warning: import cycle between %s\n", strings.Join(cycle, ", "))
+		}
+		orderSections(data.Files, graph)
+		data.Graph = graph.String()
+	}
+	// Tests are arranged first so that grouping keeps them inside their
+	// module and the headings land on each module's first file
+	data.Files = groupByModule(arrangeTests(data.Files, opts.tests))
 
-	// Walk through the directory
-	err = filepath.Walk(absPath, func(path string, info os.FileInfo, err error) error {
+	if opts.outputType == outputSeparate {
+		outputDir := strings.TrimSuffix(outputFileName, ".md")
+		count, err := writeSeparate(outputDir, tmpl, data, opts)
 		if err != nil {
 			return err
 		}
-		if info.IsDir() {
-			if strings.HasPrefix(info.Name(), ".") {
-				return filepath.SkipDir
-			}
-			return nil
+		progress.stop()
+		fmt.Printf("Rollup complete. Wrote %d rollups and an index to %s\n", count, outputDir)
+	} else {
+		// Open the output file
+		outputFile, err := os.Create(outputFileName)
+		if err != nil {
+			return fmt.Errorf("error creating output file: %v", err)
 		}
-		relPath, _ := filepath.Rel(absPath, path)
+		defer outputFile.Close()
 
-		// Check if the file should be ignored
-		if isIgnored(relPath, ignoreList) {
-			if verbose {
-				fmt.Printf("Ignoring file: %s\n", relPath)
-			}
-			return nil
+		if err := renderRollup(outputFile, tmpl, data, opts); err != nil {
+			return err
 		}
+		progress.stop()
+		fmt.Printf("Rollup complete. Output file: %s\n", outputFileName)
+	}
 
-		ext := filepath.Ext(path)
-		for _, t := range types {
-			if ext == "."+t {
-				// Verbose logging for processed file
-				if verbose {
-					size := humanReadableSize(info.Size())
-					fmt.Printf("Processing file: %s (%s)\n", relPath, size)
-				}
+	stats.print(os.Stdout)
+	if chunksPath != "" {
+		count, err := writeChunks(chunksPath, data.Files, opts)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d chunks written to %s\n", count, chunksPath)
+	}
+	if statsJSON != "" {
+		if err := stats.writeJSON(statsJSON); err != nil {
+			return err
+		}
+		fmt.Printf("Stats written to %s\n", statsJSON)
+	}
+	return nil
+}
 
-				// Read file contents
-				content, err := os.ReadFile(path)
-				if err != nil {
-					fmt.Printf("Error reading file %s: %v\n", path, err)
-					return nil
-				}
+// renderRollup completes data with the tree and framing of its files and
+// renders it into w
+func renderRollup(w io.Writer, tmpl *template.Template, data *templateData, opts *rollupOptions) error {
+	paths := make([]string, len(data.Files))
+	for i, f := range data.Files {
+		paths[i] = f.Path
+	}
+	data.Tree = renderTree(data.Project, paths)
+	data.Prompt = strings.TrimSpace(prompt)
+	if opts.symbolIndex {
+		data.Symbols = collectSymbols(data.Files, data.LineNumbers)
+	}
 
-				// Check if the file is code-generated
-				isCodeGen := isCodeGenerated(relPath, codeGenList)
-				codeGenNote := ""
-				if isCodeGen {
-					codeGenNote = " (Code-generated, Read-only)"
-				}
+	var err error
+	if data.Preamble, err = renderFraming("preamble", opts.preamble, data); err != nil {
+		return err
+	}
+	if data.Graph != "" {
+		data.Preamble = strings.TrimSpace(data.Graph + "\n\n" + data.Preamble)
+	}
+	if data.Postamble, err = renderFraming("postamble", opts.postamble, data); err != nil {
+		return err
+	}
+	return renderTemplate(w, tmpl, data)
+}
 
-				// Write file name and contents to the output file
-				fmt.Fprintf(outputFile, "# File: %s%s\n\n```%s\n%s```\n\n", relPath, codeGenNote, t, string(content))
-				break
-			}
-		}
+// loadSection reads one included file into the model rendered by the
+// output template
+func loadSection(entry *fileEntry, opts *rollupOptions) (*fileSection, error) {
+	section := &fileSection{
+		Path:          filepath.ToSlash(entry.relPath),
+		Lang:          languageFor(entry.relPath, opts.languageMap),
+		CodeGenerated: entry.codeGen,
+		Role:          entry.role,
+		Module:        entry.module,
+		SymlinkTarget: entry.linkTarget,
+		entry:         entry,
+	}
 
-		if !showProgress && time.Since(startTime) > 5*time.Second {
-			showProgress = true
-			fmt.Print("This is taking a while (hold tight) ")
+	// List unresolved symlinks by their target instead of their contents
+	if entry.linkTarget != "" {
+		if verbose {
+			fmt.Printf("Listing symlink: %s -> %s\n", entry.relPath, entry.linkTarget)
 		}
+		return section, nil
+	}
 
-		select {
-		case <-progressTicker.C:
-			if showProgress {
-				fmt.Print(".")
-			}
-		default:
-		}
+	// Verbose logging for processed file
+	if verbose {
+		size := humanReadableSize(entry.info.Size())
+		fmt.Printf("Processing file: %s (%s)\n", entry.relPath, size)
+	}
 
-		return nil
-	})
+	// Read file contents
+	content, err := os.ReadFile(entry.path)
 	if err != nil {
-		return fmt.Errorf("error walking through directory: %v", err)
+		return nil, err
+	}
+
+	section.Source = string(content)
+	section.Content = section.Source
+	section.Size = int64(len(content))
+	section.Lines = countLines(section.Source)
+	section.Tokens = estimateTokens(section.Source)
+	section.Metadata = fileMetadata(opts.metadata, opts.root, entry.path, entry.info, content)
+	name := filepath.Base(entry.relPath)
+	switch handler := handlerFor(opts.handlers, name); handler {
+	case "", handlerRaw:
+	case handlerNotebook:
+		// Files that are not notebooks after all go in as they are
+		if converted, err := convertNotebook(section.Source, opts.notebookOutputs); err == nil {
+			section.Notebook, section.Lang = true, "markdown"
+			section.Content, section.converted = converted, converted
+			section.Lines = countLines(converted)
+			section.Tokens = estimateTokens(converted)
+		}
+	default:
+		section.Summary = handleFile(name, handler, section.Source, opts)
+	}
+	if opts.lineNumbers {
+		section.Content = numberLines(section.Content)
+	}
+	return section, nil
+}
+
+// progressReporter tells the user a long run is still going by printing a
+// note after five seconds and a dot every half second after that
+type progressReporter struct {
+	start  time.Time
+	shown  bool
+	ticker *time.Ticker
+}
+
+func newProgressReporter() *progressReporter {
+	return &progressReporter{start: time.Now(), ticker: time.NewTicker(500 * time.Millisecond)}
+}
+
+func (p *progressReporter) tick() {
+	if !p.shown && time.Since(p.start) > 5*time.Second {
+		p.shown = true
+		fmt.Print("This is taking a while (hold tight) ")
 	}
 
-	if showProgress {
+	select {
+	case <-p.ticker.C:
+		if p.shown {
+			fmt.Print(".")
+		}
+	default:
+	}
+}
+
+// stop ends the progress output; it is safe to call more than once
+func (p *progressReporter) stop() {
+	p.ticker.Stop()
+	if p.shown {
 		fmt.Println() // Print a newline after the progress dots
+		p.shown = false
 	}
+}
 
-	fmt.Printf("Rollup complete. Output file: %s\n", outputFileName)
-	return nil
+func hasFileType(ext string, types []string) bool {
+	return slices.Contains(types, ext)
+}
+
+// readFileList reads the paths given with --from-stdin or --files-from, one
+// per line. Blank lines and duplicates are dropped; order is preserved.
+func readFileList() ([]string, error) {
+	var r io.Reader
+	if fromStdin {
+		r = stdin
+	} else {
+		f, err := os.Open(filesFrom)
+		if err != nil {
+			return nil, fmt.Errorf("error opening file list: %v", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	files := []string{}
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		line = filepath.Clean(filepath.FromSlash(line))
+		if seen[line] {
+			continue
+		}
+		seen[line] = true
+		files = append(files, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading file list: %v", err)
+	}
+	return files, nil
 }
 
 func humanReadableSize(size int64) string {
@@ -9,6 +9,21 @@ import (
 	"github.com/tnypxl/rollup/internal/config"
 )
 
+// writeTree creates files, given by their slash-separated paths relative to
+// root, along with their directories
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+}
+
 func TestMatchGlob(t *testing.T) {
 	tests := []struct {
 		pattern  string
@@ -34,52 +49,6 @@ func TestMatchGlob(t *testing.T) {
 	}
 }
 
-func TestIsCodeGenerated(t *testing.T) {
-	patterns := []string{"generated_*.go", "**/auto_*.go", "**/*_gen.go"}
-	tests := []struct {
-		path     string
-		expected bool
-	}{
-		{"generated_file.go", true},
-		{"normal_file.go", false},
-		{"subdir/auto_file.go", true},
-		{"subdir/normal_file.go", false},
-		{"pkg/models_gen.go", true},
-		{"pkg/handler.go", false},
-	}
-
-	for _, test := range tests {
-		result := isCodeGenerated(test.path, patterns)
-		if result != test.expected {
-			t.Errorf("isCodeGenerated(%q, %v) = %v; want %v", test.path, patterns, result, test.expected)
-		}
-	}
-}
-
-func TestIsIgnored(t *testing.T) {
-	patterns := []string{"*.tmp", "**/*.log", ".git/**", "vendor/**"}
-	tests := []struct {
-		path     string
-		expected bool
-	}{
-		{"file.tmp", true},
-		{"file.go", false},
-		{"subdir/file.log", true},
-		{"subdir/file.txt", false},
-		{".git/config", true},
-		{"src/.git/config", false},
-		{"vendor/package/file.go", true},
-		{"internal/vendor/file.go", false},
-	}
-
-	for _, test := range tests {
-		result := isIgnored(test.path, patterns)
-		if result != test.expected {
-			t.Errorf("isIgnored(%q, %v) = %v; want %v", test.path, patterns, result, test.expected)
-		}
-	}
-}
-
 func TestRunRollup(t *testing.T) {
 	// Create a temporary directory for testing
 	tempDir, err := os.MkdirTemp("", "rollup_test")
@@ -100,15 +69,7 @@ func TestRunRollup(t *testing.T) {
 		"vendor/lib/helper.go": "package lib\n\nfunc Helper() {}\n",
 	}
 
-	for name, content := range files {
-		path := filepath.Join(tempDir, name)
-		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-			t.Fatalf("Failed to create directory: %v", err)
-		}
-		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
-			t.Fatalf("Failed to write file: %v", err)
-		}
-	}
+	writeTree(t, tempDir, files)
 
 	// Set up test configuration
 	cfg = &config.Config{
@@ -170,3 +131,232 @@ func TestRunRollup(t *testing.T) {
 		}
 	}
 }
+
+func TestRunRollupFileList(t *testing.T) {
+	tempDir := t.TempDir()
+
+	files := map[string]string{
+		"b.go":         "package b\n",
+		"a.go":         "package a\n",
+		"notes.txt":    "notes\n",
+		"skip.tmp":     "temporary\n",
+		"unlisted.go":  "package unlisted\n",
+		"gen/model.go": "package gen\n",
+	}
+	writeTree(t, tempDir, files)
+
+	originalWd, _ := os.Getwd()
+	os.Chdir(tempDir)
+	defer os.Chdir(originalWd)
+
+	cfg = &config.Config{
+		FileExtensions:     []string{"go", "tmp"},
+		IgnorePaths:        []string{"*.tmp"},
+		CodeGeneratedPaths: []string{"gen/**"},
+	}
+	list := "b.go\n./a.go\n\nnotes.txt\nskip.tmp\nb.go\ngen/model.go\n"
+
+	tests := []struct {
+		name     string
+		noFilter bool
+		want     []string
+		notWant  []string
+	}{
+		{
+			name:    "filtered",
+			want:    []string{"# File: b.go", "# File: a.go", "# File: gen/model.go (Code-generated, Read-only)"},
+			notWant: []string{"notes.txt", "skip.tmp", "unlisted.go"},
+		},
+		{
+			name:     "no filter",
+			noFilter: true,
+			want:     []string{"# File: b.go", "# File: a.go", "# File: notes.txt", "# File: skip.tmp"},
+			notWant:  []string{"unlisted.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fromStdin, noFilter, stdin = true, tt.noFilter, strings.NewReader(list)
+			defer func() { fromStdin, noFilter, stdin = false, false, os.Stdin }()
+
+			if err := runRollup(cfg); err != nil {
+				t.Fatalf("runRollup() failed: %v", err)
+			}
+			outputFiles, _ := filepath.Glob("*.rollup.md")
+			if len(outputFiles) != 1 {
+				t.Fatalf("Expected one rollup file, found %v", outputFiles)
+			}
+			defer os.Remove(outputFiles[0])
+			data, err := os.ReadFile(outputFiles[0])
+			if err != nil {
+				t.Fatalf("Failed to read output file: %v", err)
+			}
+			content := string(data)
+
+			last := -1
+			for _, expected := range tt.want {
+				i := strings.Index(content, expected)
+				if i < 0 {
+					t.Errorf("Output file does not contain expected content: %s", expected)
+					continue
+				}
+				if i < last {
+					t.Errorf("Output file lists %s out of order", expected)
+				}
+				last = i
+			}
+			for _, unexpected := range tt.notWant {
+				if strings.Contains(content, unexpected) {
+					t.Errorf("Output file contains unexpected file: %s", unexpected)
+				}
+			}
+			if strings.Count(content, "# File: b.go") != 1 {
+				t.Errorf("Duplicate list entries should be rolled up once")
+			}
+		})
+	}
+}
+
+func TestRunRollupHidden(t *testing.T) {
+	tempDir := t.TempDir()
+
+	files := map[string]string{
+		"main.yml":                       "main: true\n",
+		".env.yml":                       "secret: true\n",
+		".github/workflows/ci.yml":       "on: push\n",
+		".github/dependabot.yml":         "version: 2\n",
+		".devcontainer/devcontainer.yml": "image: go\n",
+		".git/config.yml":                "bare: false\n",
+		"internal/.cache/entry.yml":      "cached: true\n",
+		"internal/settings/defaults.yml": "debug: false\n",
+	}
+	writeTree(t, tempDir, files)
+
+	originalWd, _ := os.Getwd()
+	os.Chdir(tempDir)
+	defer os.Chdir(originalWd)
+
+	tests := []struct {
+		name    string
+		cfg     *config.Config
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "default",
+			cfg:     &config.Config{FileExtensions: []string{"yml"}},
+			want:    []string{"main.yml", "internal/settings/defaults.yml"},
+			notWant: []string{".env.yml", ".github", ".devcontainer", ".git/", ".cache"},
+		},
+		{
+			name:    "include hidden",
+			cfg:     &config.Config{FileExtensions: []string{"yml"}, IncludeHidden: true},
+			want:    []string{"main.yml", ".env.yml", ".github/workflows/ci.yml", ".github/dependabot.yml", ".devcontainer/devcontainer.yml", "internal/.cache/entry.yml"},
+			notWant: []string{".git/"},
+		},
+		{
+			name:    "include patterns",
+			cfg:     &config.Config{FileExtensions: []string{"yml"}, IncludePaths: []string{".github/workflows/*.yml", ".devcontainer", ".git/config.yml"}},
+			want:    []string{"main.yml", ".github/workflows/ci.yml", ".devcontainer/devcontainer.yml", ".git/config.yml"},
+			notWant: []string{".env.yml", ".github/dependabot.yml", ".cache"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := runRollup(tt.cfg); err != nil {
+				t.Fatalf("runRollup() failed: %v", err)
+			}
+			outputFiles, _ := filepath.Glob("*.rollup.md")
+			if len(outputFiles) != 1 {
+				t.Fatalf("Expected one rollup file, found %v", outputFiles)
+			}
+			defer os.Remove(outputFiles[0])
+			data, err := os.ReadFile(outputFiles[0])
+			if err != nil {
+				t.Fatalf("Failed to read output file: %v", err)
+			}
+			content := string(data)
+
+			for _, expected := range tt.want {
+				if !strings.Contains(content, "# File: "+expected) {
+					t.Errorf("Output file does not contain expected file: %s", expected)
+				}
+			}
+			for _, unexpected := range tt.notWant {
+				if strings.Contains(content, "# File: "+unexpected) {
+					t.Errorf("Output file contains hidden file: %s", unexpected)
+				}
+			}
+		})
+	}
+}
+
+func TestRunRollupDirOverrides(t *testing.T) {
+	tempDir := t.TempDir()
+
+	files := map[string]string{
+		"main.go":                  "package main\n",
+		"README.md":                "# Project\n",
+		"docs/guide.md":            "# Guide\n",
+		"docs/example.go":          "package example\n",
+		"docs/draft.md":            "# Draft\n",
+		"docs/.rollup.yml":         "file_extensions:\n  - md\n",
+		"docs/.rollupignore":       "# work in progress\ndraft.md\n",
+		"web/app.ts":               "export {}\n",
+		"web/gen/api.ts":           "export {}\n",
+		"web/main.go":              "package web\n",
+		"web/.rollup.yml":          "file_extensions: [ts]\ncode_generated_paths: [gen/**]\n",
+		"tools/tool.go":            "package tools\n",
+		"tools/tool_test.go":       "package tools\n",
+		"tools/.rollupignore":      "!*_test.go\n",
+		"tools/deep/keep.go":       "package deep\n",
+		"tools/deep/skip.go":       "package deep\n",
+		"tools/deep/.rollupignore": "/skip.go\n",
+	}
+	writeTree(t, tempDir, files)
+
+	originalWd, _ := os.Getwd()
+	os.Chdir(tempDir)
+	defer os.Chdir(originalWd)
+
+	cfg = &config.Config{
+		FileExtensions: []string{"go", "md"},
+		IgnorePaths:    []string{"*_test.go"},
+	}
+	if err := runRollup(cfg); err != nil {
+		t.Fatalf("runRollup() failed: %v", err)
+	}
+	outputFiles, _ := filepath.Glob("*.rollup.md")
+	if len(outputFiles) != 1 {
+		t.Fatalf("Expected one rollup file, found %v", outputFiles)
+	}
+	data, err := os.ReadFile(outputFiles[0])
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	content := string(data)
+
+	expected := []string{
+		"# File: main.go\n",
+		"# File: README.md\n",
+		"# File: docs/guide.md\n",
+		"# File: web/app.ts\n",
+		"# File: web/gen/api.ts (Code-generated, Read-only)\n",
+		"# File: tools/tool.go\n",
+		"# File: tools/tool_test.go\n",
+		"# File: tools/deep/keep.go\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(content, e) {
+			t.Errorf("Output file does not contain expected content: %q", e)
+		}
+	}
+	unexpected := []string{"docs/example.go", "docs/draft.md", "web/main.go", "tools/deep/skip.go"}
+	for _, u := range unexpected {
+		if strings.Contains(content, "# File: "+u) {
+			t.Errorf("Output file contains overridden file: %s", u)
+		}
+	}
+}
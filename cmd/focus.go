@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// reasonOutsideFocus excludes files of packages the focus does not reach
+const reasonOutsideFocus = "outside focus"
+
+// goModule is a Go module on disk
+type goModule struct {
+	// root is the absolute directory holding go.mod
+	root string
+
+	// path is the module path declared in go.mod
+	path string
+}
+
+// focusSet is the set of package directories reachable from the --focus
+// entry points through imports within their module
+type focusSet struct {
+	// dirs maps each absolute package directory to its import distance
+	// from the nearest entry point
+	dirs map[string]int
+
+	tests bool
+
+	// entries are the entry points as given, for explaining decisions
+	entries []string
+}
+
+// findModule returns the module holding dir by looking for go.mod in dir
+// and its parents
+func findModule(dir string) (*goModule, error) {
+	for d := dir; ; d = filepath.Dir(d) {
+		goMod := filepath.Join(d, "go.mod")
+		if _, err := os.Stat(goMod); err == nil {
+			modPath, err := readModulePath(goMod)
+			if err != nil {
+				return nil, err
+			}
+			return &goModule{root: d, path: modPath}, nil
+		}
+		if filepath.Dir(d) == d {
+			return nil, fmt.Errorf("no go.mod found above %s", dir)
+		}
+	}
+}
+
+// readModulePath returns the path in the module directive of a go.mod file
+func readModulePath(goMod string) (string, error) {
+	f, err := os.Open(goMod)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		rest, ok := strings.CutPrefix(line, "module")
+		if !ok || rest == "" || (rest[0] != ' ' && rest[0] != '\t' && rest[0] != '"') {
+			continue
+		}
+		rest = strings.TrimSpace(rest)
+		if unquoted, err := strconv.Unquote(rest); err == nil {
+			rest = unquoted
+		}
+		return rest, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%s has no module directive", goMod)
+}
+
+// packageImports returns the import paths of the Go files in dir,
+// including those of its test files when tests is set
+func packageImports(dir string, tests bool) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var imports []string
+	fset := token.NewFileSet()
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || (!tests && strings.HasSuffix(name, "_test.go")) {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ImportsOnly)
+		if err != nil {
+			return nil, err
+		}
+		for _, spec := range file.Imports {
+			importPath, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || seen[importPath] {
+				continue
+			}
+			seen[importPath] = true
+			imports = append(imports, importPath)
+		}
+	}
+	sort.Strings(imports)
+	return imports, nil
+}
+
+// resolveFocus follows the imports of each entry point, a Go file or
+// package directory relative to root, through its module. A maxDepth
+// above 0 limits how many imports away from an entry point packages are
+// included.
+func resolveFocus(root string, entries []string, maxDepth int, tests bool) (*focusSet, error) {
+	focus := &focusSet{dirs: make(map[string]int), tests: tests, entries: entries}
+
+	type queued struct {
+		dir   string
+		depth int
+		mod   *goModule
+	}
+	var queue []queued
+	for _, entry := range entries {
+		abs := entry
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(root, abs)
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, fmt.Errorf("invalid focus: %v", err)
+		}
+		dir := abs
+		if !info.IsDir() {
+			dir = filepath.Dir(abs)
+		}
+		mod, err := findModule(dir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid focus %s: %v", entry, err)
+		}
+		queue = append(queue, queued{dir, 0, mod})
+	}
+
+	// Breadth first, so each package is recorded at its shortest distance
+	for len(queue) > 0 {
+		q := queue[0]
+		queue = queue[1:]
+		if _, seen := focus.dirs[q.dir]; seen {
+			continue
+		}
+		focus.dirs[q.dir] = q.depth
+		if maxDepth > 0 && q.depth >= maxDepth {
+			continue
+		}
+
+		imports, err := packageImports(q.dir, tests)
+		if err != nil {
+			// A package that does not parse is kept without its imports
+			fmt.Printf("Warning: ignoring the imports of %s: %v\n", q.dir, err)
+		}
+		for _, importPath := range imports {
+			rest, ok := strings.CutPrefix(importPath, q.mod.path)
+			if !ok || (rest != "" && rest[0] != '/') {
+				continue
+			}
+			dir := filepath.Join(q.mod.root, filepath.FromSlash(rest))
+			if _, seen := focus.dirs[dir]; !seen {
+				queue = append(queue, queued{dir, q.depth + 1, q.mod})
+			}
+		}
+	}
+	return focus, nil
+}
+
+// decide reports whether the file at the absolute path filePath is in
+// focus, and the rule behind the decision
+func (f *focusSet) decide(filePath string) (bool, string) {
+	from := "focus " + strings.Join(f.entries, ", ")
+	depth, ok := f.dirs[filepath.Dir(filePath)]
+	if !ok {
+		return false, "package not imported from " + from
+	}
+	if !f.tests && strings.HasSuffix(filePath, "_test.go") {
+		return false, "test file; tests are included with focus_tests"
+	}
+	if depth == 0 {
+		return true, "package of " + from
+	}
+	if depth == 1 {
+		return true, "package imported directly from " + from
+	}
+	return true, fmt.Sprintf("package %d imports away from %s", depth, from)
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/tnypxl/rollup/internal/config"
+)
+
+func TestReadModulePath(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+		wantErr bool
+	}{
+		{"module example.com/app\n\ngo 1.23\n", "example.com/app", false},
+		{"// comment\nmodule \"example.com/quoted\" // trailing\n", "example.com/quoted", false},
+		{"modules example.com/nope\n", "", true},
+		{"go 1.23\n", "", true},
+	}
+
+	for _, tt := range tests {
+		goMod := filepath.Join(t.TempDir(), "go.mod")
+		if err := os.WriteFile(goMod, []byte(tt.content), 0o644); err != nil {
+			t.Fatalf("Failed to write go.mod: %v", err)
+		}
+		got, err := readModulePath(goMod)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("readModulePath(%q) error = %v, wantErr %v", tt.content, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("readModulePath(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestResolveFocus(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                    "module example.com/app\n",
+		"cmd/app/main.go":           "package main\n\nimport (\n\t\"fmt\"\n\t\"example.com/app/internal/a\"\n)\n",
+		"internal/a/a.go":           "package a\n\nimport \"example.com/app/internal/b\"\n",
+		"internal/a/a_test":         "not go",
+		"internal/b/b.go":           "package b\n\nimport \"example.com/other/c\"\n",
+		"internal/b/b_test.go":      "package b\n\nimport \"example.com/app/internal/testutil\"\n",
+		"internal/testutil/util.go": "package testutil\n",
+		"internal/unused/unused.go": "package unused\n",
+	}
+	writeTree(t, tempDir, files)
+
+	tests := []struct {
+		name  string
+		depth int
+		tests bool
+		want  map[string]int
+	}{
+		{
+			name: "transitive",
+			want: map[string]int{"cmd/app": 0, "internal/a": 1, "internal/b": 2},
+		},
+		{
+			name:  "depth limit",
+			depth: 1,
+			want:  map[string]int{"cmd/app": 0, "internal/a": 1},
+		},
+		{
+			name:  "with tests",
+			tests: true,
+			want:  map[string]int{"cmd/app": 0, "internal/a": 1, "internal/b": 2, "internal/testutil": 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			focus, err := resolveFocus(tempDir, []string{filepath.Join(tempDir, "cmd/app/main.go")}, tt.depth, tt.tests)
+			if err != nil {
+				t.Fatalf("resolveFocus() returned error: %v", err)
+			}
+			got := make(map[string]int)
+			for dir, depth := range focus.dirs {
+				rel, _ := filepath.Rel(tempDir, dir)
+				got[filepath.ToSlash(rel)] = depth
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveFocus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	focus, err := resolveFocus(tempDir, []string{filepath.Join(tempDir, "internal/b")}, 0, false)
+	if err != nil {
+		t.Fatalf("resolveFocus() returned error: %v", err)
+	}
+	var included []string
+	for _, name := range []string{"internal/b/b.go", "internal/b/b_test.go", "internal/a/a.go"} {
+		if ok, _ := focus.decide(filepath.Join(tempDir, name)); ok {
+			included = append(included, name)
+		}
+	}
+	sort.Strings(included)
+	if want := []string{"internal/b/b.go"}; !reflect.DeepEqual(included, want) {
+		t.Errorf("files in focus = %v, want %v", included, want)
+	}
+
+	// A package that does not parse is in focus without its imports
+	writeTree(t, tempDir, map[string]string{
+		"internal/c/c.go":        "package c\n\nimport \"example.com/app/internal/broken\"\n",
+		"internal/broken/bad.go": "this is not go {{{\n",
+	})
+	focus, err = resolveFocus(tempDir, []string{"internal/c"}, 0, false)
+	if err != nil {
+		t.Fatalf("resolveFocus() with a package that does not parse returned error: %v", err)
+	}
+	if _, ok := focus.dirs[filepath.Join(tempDir, "internal/broken")]; !ok || len(focus.dirs) != 2 {
+		t.Errorf("resolveFocus() with a package that does not parse = %v", focus.dirs)
+	}
+
+	// Relative entries are resolved against the project root, not the
+	// working directory
+	focus, err = resolveFocus(tempDir, []string{"internal/a"}, 0, false)
+	if err != nil {
+		t.Fatalf("resolveFocus() with a relative entry returned error: %v", err)
+	}
+	if _, ok := focus.dirs[filepath.Join(tempDir, "internal/b")]; !ok || len(focus.dirs) != 2 {
+		t.Errorf("resolveFocus() with a relative entry = %v", focus.dirs)
+	}
+
+	if _, err := resolveFocus(t.TempDir(), []string{"."}, 0, false); err == nil {
+		t.Errorf("resolveFocus() outside a module returned no error")
+	}
+}
+
+func TestRunRollupFocusPath(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTree(t, tempDir, map[string]string{
+		"project/go.mod":          "module example.com/app\n",
+		"project/internal/a/a.go": "package a\n\nimport \"example.com/app/internal/b\"\n",
+		"project/internal/b/b.go": "package b\n",
+		"project/internal/c/c.go": "package c\n",
+	})
+
+	originalWd, _ := os.Getwd()
+	os.Chdir(tempDir)
+	defer os.Chdir(originalWd)
+	originalPath := path
+	path = "project"
+	defer func() { path = originalPath }()
+
+	// The focus is relative to --path, not to the working directory
+	cfg = &config.Config{FileExtensions: []string{"go"}, Focus: []string{"internal/a"}}
+	if err := runRollup(cfg); err != nil {
+		t.Fatalf("runRollup() failed: %v", err)
+	}
+	outputFiles, _ := filepath.Glob("*.rollup.md")
+	if len(outputFiles) != 1 {
+		t.Fatalf("Expected one rollup file, found %v", outputFiles)
+	}
+	data, err := os.ReadFile(outputFiles[0])
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	content := string(data)
+	for _, name := range []string{"internal/a/a.go", "internal/b/b.go"} {
+		if !strings.Contains(content, "# File: "+name) {
+			t.Errorf("Output file does not contain %s", name)
+		}
+	}
+	if strings.Contains(content, "internal/c/c.go") {
+		t.Errorf("Output file contains internal/c/c.go, which is outside the focus")
+	}
+}
@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// globPattern is a single compiled ignore, include or code-generated pattern
+type globPattern struct {
+	// raw is the pattern as written
+	raw string
+
+	// alternatives holds the pattern with brace alternation expanded
+	alternatives []string
+
+	// negate re-includes paths matched by an earlier pattern (!pattern)
+	negate bool
+
+	// dirOnly matches directories only (pattern/)
+	dirOnly bool
+
+	// anchored patterns contain a slash and are matched from the project
+	// root; other patterns match a file or directory name at any depth
+	anchored bool
+}
+
+// globMatcher matches paths against an ordered list of gitignore-style
+// patterns. Supported syntax is *, ?, [classes], ** segments, {a,b}
+// alternation, a leading ! to re-include, a leading / to anchor and a
+// trailing / to match directories only. The last matching pattern wins.
+type globMatcher struct {
+	patterns        []globPattern
+	caseInsensitive bool
+}
+
+func newGlobMatcher(patterns []string, caseInsensitive bool) *globMatcher {
+	m := &globMatcher{caseInsensitive: caseInsensitive}
+	for _, raw := range patterns {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		p := globPattern{raw: raw}
+		if caseInsensitive {
+			raw = strings.ToLower(raw)
+		}
+
+		switch {
+		case strings.HasPrefix(raw, "!"):
+			p.negate = true
+			raw = raw[1:]
+		case strings.HasPrefix(raw, `\!`):
+			raw = raw[1:]
+		}
+		if strings.HasSuffix(raw, "/") {
+			p.dirOnly = true
+			raw = strings.TrimRight(raw, "/")
+		}
+		if strings.HasPrefix(raw, "/") {
+			p.anchored = true
+			raw = strings.TrimLeft(raw, "/")
+		}
+		if raw == "" {
+			continue
+		}
+		if strings.Contains(raw, "/") {
+			p.anchored = true
+		}
+		p.alternatives = expandBraces(raw)
+		m.patterns = append(m.patterns, p)
+	}
+	return m
+}
+
+// Match reports whether relPath is selected by the patterns. isDir tells
+// whether relPath itself is a directory, for directory-only patterns.
+func (m *globMatcher) Match(relPath string, isDir bool) bool {
+	_, matched := m.Rule(relPath, isDir)
+	return matched
+}
+
+// Rule returns the index of the last pattern that matched relPath, or -1,
+// along with the resulting decision.
+func (m *globMatcher) Rule(relPath string, isDir bool) (int, bool) {
+	relPath = filepath.ToSlash(relPath)
+	if m.caseInsensitive {
+		relPath = strings.ToLower(relPath)
+	}
+	parts := strings.Split(relPath, "/")
+
+	rule, matched := -1, false
+	for i, p := range m.patterns {
+		if p.matches(parts, isDir) {
+			rule, matched = i, !p.negate
+		}
+	}
+	return rule, matched
+}
+
+// Pattern returns the i-th pattern as written, for reporting which rule
+// decided a path
+func (m *globMatcher) Pattern(i int) string {
+	if i < 0 || i >= len(m.patterns) {
+		return ""
+	}
+	return m.patterns[i].raw
+}
+
+func (p globPattern) matches(parts []string, isDir bool) bool {
+	for i := range parts {
+		// Only the last element can be a file; everything before it is a
+		// directory containing the path.
+		last := i == len(parts)-1
+		if last && p.dirOnly && !isDir {
+			return false
+		}
+		for _, alt := range p.alternatives {
+			var matched bool
+			if p.anchored {
+				matched = matchGlob(alt, strings.Join(parts[:i+1], "/"))
+			} else {
+				matched, _ = filepath.Match(alt, parts[i])
+			}
+			if matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MayMatchBelow reports whether a pattern could match a path inside
+// dirPath, so the directory has to be walked even if it is skipped
+// otherwise. Only patterns that name .git lead into a .git directory.
+func (m *globMatcher) MayMatchBelow(dirPath string) bool {
+	dirPath = filepath.ToSlash(dirPath)
+	if m.caseInsensitive {
+		dirPath = strings.ToLower(dirPath)
+	}
+	dirParts := strings.Split(dirPath, "/")
+	inGit := isGitPath(dirPath)
+
+	for _, p := range m.patterns {
+		if p.negate {
+			continue
+		}
+		for _, alt := range p.alternatives {
+			patternParts := strings.Split(alt, "/")
+			if inGit && !namesGit(patternParts) {
+				continue
+			}
+			if !p.anchored || mayMatchBelow(patternParts, dirParts) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// namesGit reports whether a pattern has a .git segment
+func namesGit(patternParts []string) bool {
+	for _, part := range patternParts {
+		if part == ".git" {
+			return true
+		}
+	}
+	return false
+}
+
+func mayMatchBelow(patternParts, dirParts []string) bool {
+	for i, part := range dirParts {
+		if i >= len(patternParts) {
+			return false
+		}
+		if patternParts[i] == "**" {
+			return true
+		}
+		if matched, _ := filepath.Match(patternParts[i], part); !matched {
+			return false
+		}
+	}
+	return len(patternParts) > len(dirParts)
+}
+
+func matchGlob(pattern, path string) bool {
+	parts := strings.Split(pattern, "/")
+	return matchGlobRecursive(parts, path)
+}
+
+func matchGlobRecursive(patternParts []string, path string) bool {
+	if len(patternParts) == 0 {
+		return path == ""
+	}
+
+	if patternParts[0] == "**" {
+		for i := 0; i <= len(path); i++ {
+			if matchGlobRecursive(patternParts[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	i := strings.IndexByte(path, '/')
+	if i < 0 {
+		matched, _ := filepath.Match(patternParts[0], path)
+		return matched && len(patternParts) == 1
+	}
+
+	matched, _ := filepath.Match(patternParts[0], path[:i])
+	return matched && matchGlobRecursive(patternParts[1:], path[i+1:])
+}
+
+// expandBraces expands {a,b} alternation, including nested groups, into
+// the list of plain glob patterns it stands for.
+func expandBraces(pattern string) []string {
+	depth, start := 0, -1
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth > 0 {
+				continue
+			}
+			prefix, body, suffix := pattern[:start], pattern[start+1:i], pattern[i+1:]
+			var expanded []string
+			for _, alt := range splitAlternatives(body) {
+				for _, rest := range expandBraces(alt + suffix) {
+					expanded = append(expanded, prefix+rest)
+				}
+			}
+			return expanded
+		}
+	}
+	return []string{pattern}
+}
+
+// splitAlternatives splits the body of a brace group on its top-level commas
+func splitAlternatives(body string) []string {
+	var alts []string
+	depth, start := 0, 0
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				alts = append(alts, body[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(alts, body[start:])
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"*.go", []string{"*.go"}},
+		{"*.{yml,yaml}", []string{"*.yml", "*.yaml"}},
+		{"{cmd,internal}/**/*.{go,md}", []string{"cmd/**/*.go", "cmd/**/*.md", "internal/**/*.go", "internal/**/*.md"}},
+		{"a{b,c{d,e}}f", []string{"abf", "acdf", "acef"}},
+		{"{,x}y", []string{"y", "xy"}},
+		{`\{a,b\}`, []string{`\{a,b\}`}},
+		{"unclosed{a,b", []string{"unclosed{a,b"}},
+	}
+
+	for _, test := range tests {
+		result := expandBraces(test.pattern)
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("expandBraces(%q) = %v; want %v", test.pattern, result, test.expected)
+		}
+	}
+}
+
+func TestGlobMatcher(t *testing.T) {
+	tests := []struct {
+		name            string
+		patterns        []string
+		caseInsensitive bool
+		path            string
+		isDir           bool
+		expected        bool
+	}{
+		{"name at any depth", []string{"*.log"}, false, "a/b/debug.log", false, true},
+		{"name matches parent dir", []string{"node_modules"}, false, "web/node_modules/x/index.js", false, true},
+		{"anchored does not float", []string{"docs/*.md"}, false, "src/docs/readme.md", false, false},
+		{"leading slash anchors", []string{"/build"}, false, "build/out.txt", false, true},
+		{"leading slash anchors only root", []string{"/build"}, false, "web/build/out.txt", false, false},
+		{"double star prefix", []string{"**/testdata/**"}, false, "pkg/a/testdata/in.json", false, true},
+		{"double star suffix excludes dir itself", []string{"vendor/**"}, false, "vendor", true, false},
+		{"double star middle", []string{"a/**/b.go"}, false, "a/b.go", false, true},
+		{"brace alternation", []string{"*.{yml,yaml}"}, false, "ci/deploy.yaml", false, true},
+		{"brace alternation miss", []string{"*.{yml,yaml}"}, false, "ci/deploy.json", false, false},
+		{"dir only matches dir", []string{"build/"}, false, "build", true, true},
+		{"dir only matches contents", []string{"build/"}, false, "build/app.bin", false, true},
+		{"dir only skips file", []string{"build/"}, false, "build", false, false},
+		{"negation re-includes", []string{"*.md", "!README.md"}, false, "README.md", false, false},
+		{"negation keeps others", []string{"*.md", "!README.md"}, false, "CHANGELOG.md", false, true},
+		{"last match wins", []string{"*.md", "!README.md", "docs/**"}, false, "docs/README.md", false, true},
+		{"negation alone matches nothing", []string{"!*.go"}, false, "main.go", false, false},
+		{"escaped bang is literal", []string{`\!important.txt`}, false, "!important.txt", false, true},
+		{"case sensitive by default", []string{"*.MD"}, false, "readme.md", false, false},
+		{"case insensitive", []string{"*.MD"}, true, "docs/README.md", false, true},
+		{"case insensitive classes", []string{"[A-C]*.go"}, true, "beta.go", false, true},
+		{"blank patterns are skipped", []string{"", "  "}, false, "main.go", false, false},
+		{"question mark", []string{"file?.go"}, false, "file1.go", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newGlobMatcher(tt.patterns, tt.caseInsensitive)
+			if result := m.Match(tt.path, tt.isDir); result != tt.expected {
+				t.Errorf("Match(%q) with %v = %v; want %v", tt.path, tt.patterns, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGlobMatcherMayMatchBelow(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		dir      string
+		expected bool
+	}{
+		{[]string{".github/workflows/*.yml"}, ".github", true},
+		{[]string{".github/workflows/*.yml"}, ".github/workflows", true},
+		{[]string{".github/workflows/*.yml"}, ".git", false},
+		{[]string{".github/workflows/*.yml"}, ".github/workflows/nested", false},
+		{[]string{"{.github,.circleci}/**"}, ".circleci", true},
+		{[]string{"**/.config/*"}, "a/.cache", true},
+		{[]string{".devcontainer"}, ".vscode", true},
+		{[]string{"!.github/**"}, ".github", false},
+		{[]string{".env"}, ".git", false},
+		{[]string{"**/*.yml"}, "sub/.git/hooks", false},
+		{[]string{".git/config"}, ".git", true},
+		{[]string{"**/.git/HEAD"}, "sub/.git", true},
+	}
+
+	for _, test := range tests {
+		m := newGlobMatcher(test.patterns, false)
+		if result := m.MayMatchBelow(test.dir); result != test.expected {
+			t.Errorf("MayMatchBelow(%q) with %v = %v; want %v", test.dir, test.patterns, result, test.expected)
+		}
+	}
+}
+
+func TestGlobMatcherCodeGeneratedPatterns(t *testing.T) {
+	patterns := []string{"generated_*.go", "**/auto_*.go", "**/*_gen.go"}
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"generated_file.go", true},
+		{"normal_file.go", false},
+		{"subdir/auto_file.go", true},
+		{"subdir/normal_file.go", false},
+		{"pkg/models_gen.go", true},
+		{"pkg/handler.go", false},
+	}
+
+	m := newGlobMatcher(patterns, false)
+	for _, test := range tests {
+		result := m.Match(test.path, false)
+		if result != test.expected {
+			t.Errorf("Match(%q) with %v = %v; want %v", test.path, patterns, result, test.expected)
+		}
+	}
+}
+
+func TestGlobMatcherIgnorePatterns(t *testing.T) {
+	patterns := []string{"*.tmp", "**/*.log", ".git/**", "vendor/**"}
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"file.tmp", true},
+		{"file.go", false},
+		{"subdir/file.log", true},
+		{"subdir/file.txt", false},
+		{".git/config", true},
+		{"src/.git/config", false},
+		{"vendor/package/file.go", true},
+		{"internal/vendor/file.go", false},
+	}
+
+	m := newGlobMatcher(patterns, false)
+	for _, test := range tests {
+		result := m.Match(test.path, false)
+		if result != test.expected {
+			t.Errorf("Match(%q) with %v = %v; want %v", test.path, patterns, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// languageByExtension maps file extensions to the language names commonly
+// recognised in fenced code blocks
+var languageByExtension = map[string]string{
+	// Text and docs
+	"txt": "text", "md": "markdown", "markdown": "markdown", "rst": "rst",
+	"tex": "latex", "adoc": "asciidoc",
+
+	// Web
+	"html": "html", "htm": "html", "css": "css", "scss": "scss", "sass": "sass",
+	"less": "less", "js": "javascript", "mjs": "javascript", "cjs": "javascript",
+	"jsx": "jsx", "ts": "typescript", "mts": "typescript", "cts": "typescript",
+	"tsx": "tsx", "vue": "vue", "svelte": "svelte", "graphql": "graphql", "gql": "graphql",
+
+	// Data and config
+	"json": "json", "jsonc": "jsonc", "xml": "xml", "yaml": "yaml", "yml": "yaml",
+	"toml": "toml", "ini": "ini", "cfg": "ini", "conf": "ini", "env": "dotenv",
+	"properties": "properties", "csv": "csv", "tsv": "tsv", "proto": "protobuf",
+	"sql": "sql", "tf": "hcl", "tfvars": "hcl", "hcl": "hcl",
+
+	// Shells and build files
+	"sh": "sh", "bash": "bash", "zsh": "zsh", "fish": "fish", "ps1": "powershell",
+	"bat": "batch", "cmd": "batch", "mk": "makefile", "make": "makefile",
+	"cmake": "cmake", "dockerfile": "dockerfile", "gradle": "groovy", "nix": "nix",
+
+	// Programming languages
+	"go": "go", "py": "python", "pyi": "python", "rb": "ruby", "php": "php",
+	"java": "java", "kt": "kotlin", "kts": "kotlin", "scala": "scala", "groovy": "groovy",
+	"c": "c", "h": "c", "cpp": "cpp", "cc": "cpp", "cxx": "cpp", "hpp": "cpp",
+	"hh": "cpp", "hxx": "cpp", "m": "objectivec", "mm": "objectivec", "cs": "csharp",
+	"fs": "fsharp", "vb": "vbnet", "rs": "rust", "swift": "swift", "dart": "dart",
+	"lua": "lua", "pl": "perl", "pm": "perl", "r": "r", "jl": "julia", "ex": "elixir",
+	"exs": "elixir", "erl": "erlang", "hrl": "erlang", "hs": "haskell", "ml": "ocaml",
+	"clj": "clojure", "cljs": "clojure", "el": "elisp", "vim": "vim", "zig": "zig",
+	"nim": "nim", "sol": "solidity",
+}
+
+// languageByFilename maps well-known file names without a telling extension
+var languageByFilename = map[string]string{
+	"Makefile": "makefile", "GNUmakefile": "makefile", "makefile": "makefile",
+	"Dockerfile": "dockerfile", "Containerfile": "dockerfile",
+	"Jenkinsfile": "groovy", "Vagrantfile": "ruby", "Gemfile": "ruby", "Rakefile": "ruby",
+	"CMakeLists.txt": "cmake", "BUILD": "starlark", "BUILD.bazel": "starlark",
+	"WORKSPACE": "starlark", ".bashrc": "bash", ".zshrc": "zsh", ".gitignore": "gitignore",
+	".rollupignore": "gitignore", ".editorconfig": "ini", ".env": "dotenv",
+}
+
+// languageFor returns the fence language for filePath. Entries in overrides
+// (the language_map setting) are keyed by file name or by extension without
+// the leading dot and take precedence over the built-in tables. Unknown
+// extensions are used as-is.
+func languageFor(filePath string, overrides map[string]string) string {
+	name := filepath.Base(filePath)
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))
+
+	if lang, ok := overrides[name]; ok {
+		return lang
+	}
+	if lang, ok := overrides[ext]; ok && ext != "" {
+		return lang
+	}
+	if lang, ok := languageByFilename[name]; ok {
+		return lang
+	}
+	if lang, ok := languageByExtension[ext]; ok {
+		return lang
+	}
+	return ext
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import "testing"
+
+func TestLanguageFor(t *testing.T) {
+	overrides := map[string]string{
+		"tsx":      "typescript",
+		"Taskfile": "yaml",
+		"tmpl":     "go-template",
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"main.go", "go"},
+		{"config/app.yml", "yaml"},
+		{"include/util.h", "c"},
+		{"build/rules.mk", "makefile"},
+		{"android/App.kt", "kotlin"},
+		{"README.MD", "markdown"},
+		{"Makefile", "makefile"},
+		{"docker/Dockerfile", "dockerfile"},
+		{"CMakeLists.txt", "cmake"},
+		{"web/App.tsx", "typescript"},
+		{"Taskfile", "yaml"},
+		{"templates/page.tmpl", "go-template"},
+		{"data/archive.xyz", "xyz"},
+		{"LICENSE", ""},
+	}
+
+	for _, test := range tests {
+		result := languageFor(test.path, overrides)
+		if result != test.expected {
+			t.Errorf("languageFor(%q) = %q; want %q", test.path, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,420 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Handlers for manifests, lockfiles, notebooks and data files, set per
+// file name or extension with file_handlers
+const (
+	handlerRaw      = "raw"
+	handlerManifest = "manifest"
+	handlerSummary  = "summary"
+	handlerNotebook = "notebook"
+	handlerSample   = "sample"
+	handlerSchema   = "schema"
+)
+
+// fileHandlers lists every handler, in the order they are documented
+var fileHandlers = []string{handlerRaw, handlerManifest, handlerSummary, handlerNotebook, handlerSample, handlerSchema}
+
+// defaultHandlers lists the file names and extensions rendered as
+// something other than their raw content unless file_handlers says
+// otherwise
+var defaultHandlers = map[string]string{
+	"ipynb": handlerNotebook,
+
+	"csv":  handlerSample,
+	"tsv":  handlerSample,
+	"json": handlerSchema,
+	"yaml": handlerSchema,
+	"yml":  handlerSchema,
+
+	"go.mod":           handlerManifest,
+	"package.json":     handlerManifest,
+	"Cargo.toml":       handlerManifest,
+	"requirements.txt": handlerManifest,
+
+	"go.sum":            handlerSummary,
+	"go.work.sum":       handlerSummary,
+	"package-lock.json": handlerSummary,
+	"yarn.lock":         handlerSummary,
+	"pnpm-lock.yaml":    handlerSummary,
+	"Cargo.lock":        handlerSummary,
+	"poetry.lock":       handlerSummary,
+	"Gemfile.lock":      handlerSummary,
+	"composer.lock":     handlerSummary,
+}
+
+// dependency is one row of a manifest's dependency table
+type dependency struct {
+	Name    string
+	Version string
+	Kind    string
+}
+
+// manifestParsers read the dependencies of the manifests rollup knows
+var manifestParsers = map[string]func(content string) (string, []dependency, error){
+	"go.mod":           parseGoMod,
+	"package.json":     parsePackageJSON,
+	"Cargo.toml":       parseCargoToml,
+	"requirements.txt": parseRequirements,
+}
+
+// resolveHandlers merges configured handlers over the defaults, checking
+// that every manifest handler is for a file rollup can parse. With raw,
+// manifests and lockfiles are not handled unless configured.
+func resolveHandlers(configured map[string]string, raw bool) (map[string]string, error) {
+	handlers := make(map[string]string)
+	for name, handler := range defaultHandlers {
+		if !raw || (handler != handlerManifest && handler != handlerSummary) {
+			handlers[name] = handler
+		}
+	}
+	for name, handler := range configured {
+		if !slices.Contains(fileHandlers, handler) {
+			return nil, fmt.Errorf("invalid handler %q for %s: must be one of %s", handler, name, strings.Join(fileHandlers, ", "))
+		}
+		if handler == handlerManifest && manifestParsers[name] == nil {
+			return nil, fmt.Errorf("no manifest handler for %s: manifests can be go.mod, package.json, Cargo.toml or requirements.txt", name)
+		}
+		handlers[name] = handler
+	}
+	return handlers, nil
+}
+
+// handlerFor returns the handler of a file by its name, or else by its
+// extension (without leading dot), as language_map does
+func handlerFor(handlers map[string]string, name string) string {
+	if handler, ok := handlers[name]; ok {
+		return handler
+	}
+	if ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(name), ".")); ext != "" {
+		return handlers[ext]
+	}
+	return ""
+}
+
+// handleFile renders a file with a manifest, summary, sample or schema
+// handler, returning "" when the file goes into the rollup as is. Data
+// files are only sampled or summarized from opts.dataThreshold up.
+func handleFile(name, handler, content string, opts *rollupOptions) string {
+	switch handler {
+	case handlerManifest:
+		title, deps, err := manifestParsers[name](content)
+		if err != nil {
+			// A manifest that does not parse is better shown as is
+			return ""
+		}
+		return manifestTable(title, deps)
+	case handlerSummary:
+		return lockfileSummary(name, content)
+	case handlerSample, handlerSchema:
+		if int64(len(content)) < opts.dataThreshold {
+			return ""
+		}
+		var summary string
+		var err error
+		if handler == handlerSample {
+			summary, err = sampleTable(name, content, opts.sampleRows, opts.randomRows)
+		} else {
+			summary, err = summarizeSchema(name, content)
+		}
+		if err != nil {
+			return ""
+		}
+		return summary
+	}
+	return ""
+}
+
+// manifestTable renders a manifest's title line and dependencies
+func manifestTable(title string, deps []dependency) string {
+	var b strings.Builder
+	if title != "" {
+		b.WriteString(title + "\n\n")
+	}
+	if len(deps) == 0 {
+		b.WriteString("No dependencies.\n")
+		return b.String()
+	}
+	b.WriteString("| Dependency | Version | Kind |\n")
+	b.WriteString("|------------|---------|------|\n")
+	for _, d := range deps {
+		version := d.Version
+		if version == "" {
+			version = "-"
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | %s |\n", d.Name, version, d.Kind)
+	}
+	return b.String()
+}
+
+// lockfileSummary describes a lockfile in one line: its entries, size and
+// hash
+func lockfileSummary(name, content string) string {
+	return fmt.Sprintf("Lockfile with %d entries, %s, SHA-256 `%s`.\n",
+		lockfileEntries(name, content), humanReadableSize(int64(len(content))), hashContent(content)[:12])
+}
+
+// lockfileEntries counts the locked packages in a lockfile, falling back to
+// its non-empty lines for formats it does not know
+func lockfileEntries(name, content string) int {
+	count := 0
+	switch name {
+	case "package-lock.json":
+		var lock struct {
+			Packages     map[string]json.RawMessage `json:"packages"`
+			Dependencies map[string]json.RawMessage `json:"dependencies"`
+		}
+		if err := json.Unmarshal([]byte(content), &lock); err == nil {
+			if len(lock.Packages) > 0 {
+				// The "" key is the project itself
+				delete(lock.Packages, "")
+				return len(lock.Packages)
+			}
+			return len(lock.Dependencies)
+		}
+	case "composer.lock":
+		var lock struct {
+			Packages    []json.RawMessage `json:"packages"`
+			PackagesDev []json.RawMessage `json:"packages-dev"`
+		}
+		if err := json.Unmarshal([]byte(content), &lock); err == nil {
+			return len(lock.Packages) + len(lock.PackagesDev)
+		}
+	case "yarn.lock":
+		for _, line := range strings.Split(content, "\n") {
+			if line != "" && line[0] != ' ' && line[0] != '#' && strings.HasSuffix(line, ":") {
+				count++
+			}
+		}
+		return count
+	case "Cargo.lock", "poetry.lock":
+		return strings.Count(content, "[[package]]")
+	case "Gemfile.lock":
+		for _, line := range strings.Split(content, "\n") {
+			if strings.HasPrefix(line, "    ") && !strings.HasPrefix(line, "      ") {
+				count++
+			}
+		}
+		return count
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// parseGoMod reads the module path, Go version and requirements of go.mod
+func parseGoMod(content string) (string, []dependency, error) {
+	var title, goVersion string
+	var deps []dependency
+	inRequire := false
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		indirect := strings.Contains(line, "// indirect")
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case inRequire && fields[0] == ")":
+			inRequire = false
+		case inRequire:
+			deps = append(deps, goRequirement(fields, indirect))
+		case fields[0] == "module" && len(fields) > 1:
+			title = "Module `" + strings.Trim(fields[1], `"`) + "`"
+		case fields[0] == "go" && len(fields) > 1:
+			goVersion = fields[1]
+		case fields[0] == "require" && len(fields) > 1 && fields[1] == "(":
+			inRequire = true
+		case fields[0] == "require" && len(fields) > 1:
+			deps = append(deps, goRequirement(fields[1:], indirect))
+		}
+	}
+	if goVersion != "" {
+		title += ", Go " + goVersion
+	}
+	return title, deps, scanner.Err()
+}
+
+func goRequirement(fields []string, indirect bool) dependency {
+	d := dependency{Name: fields[0], Kind: "direct"}
+	if len(fields) > 1 {
+		d.Version = fields[1]
+	}
+	if indirect {
+		d.Kind = "indirect"
+	}
+	return d
+}
+
+// parsePackageJSON reads the name, version and dependency groups of
+// package.json
+func parsePackageJSON(content string) (string, []dependency, error) {
+	var pkg struct {
+		Name                 string            `json:"name"`
+		Version              string            `json:"version"`
+		Dependencies         map[string]string `json:"dependencies"`
+		DevDependencies      map[string]string `json:"devDependencies"`
+		PeerDependencies     map[string]string `json:"peerDependencies"`
+		OptionalDependencies map[string]string `json:"optionalDependencies"`
+	}
+	if err := json.Unmarshal([]byte(content), &pkg); err != nil {
+		return "", nil, err
+	}
+
+	title := ""
+	if pkg.Name != "" {
+		title = "Package `" + pkg.Name + "`"
+		if pkg.Version != "" {
+			title += " " + pkg.Version
+		}
+	}
+	var deps []dependency
+	for _, group := range []struct {
+		kind string
+		deps map[string]string
+	}{
+		{"runtime", pkg.Dependencies},
+		{"dev", pkg.DevDependencies},
+		{"peer", pkg.PeerDependencies},
+		{"optional", pkg.OptionalDependencies},
+	} {
+		deps = append(deps, sortedDependencies(group.deps, group.kind)...)
+	}
+	return title, deps, nil
+}
+
+func sortedDependencies(versions map[string]string, kind string) []dependency {
+	names := make([]string, 0, len(versions))
+	for name := range versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	deps := make([]dependency, len(names))
+	for i, name := range names {
+		deps[i] = dependency{Name: name, Version: versions[name], Kind: kind}
+	}
+	return deps
+}
+
+// parseCargoToml reads the package and dependency tables of Cargo.toml.
+// It understands the common forms, name = "1.0" and name = { version =
+// "1.0", ... }, as well as [dependencies.name] tables.
+func parseCargoToml(content string) (string, []dependency, error) {
+	var name, version string
+	var deps []dependency
+	section := ""
+	var tableDep *dependency
+
+	kinds := map[string]string{
+		"dependencies":       "runtime",
+		"dev-dependencies":   "dev",
+		"build-dependencies": "build",
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if tableDep != nil {
+				deps = append(deps, *tableDep)
+				tableDep = nil
+			}
+			section = strings.Trim(line, "[] ")
+			// [dependencies.serde] holds one dependency's settings
+			if group, dep, ok := strings.Cut(section, "."); ok && kinds[group] != "" {
+				tableDep = &dependency{Name: dep, Kind: kinds[group]}
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch {
+		case tableDep != nil:
+			if key == "version" {
+				tableDep.Version = strings.Trim(value, `"'`)
+			}
+		case section == "package" && key == "name":
+			name = strings.Trim(value, `"'`)
+		case section == "package" && key == "version":
+			version = strings.Trim(value, `"'`)
+		case kinds[section] != "":
+			deps = append(deps, dependency{Name: key, Version: cargoVersion(value), Kind: kinds[section]})
+		}
+	}
+	if tableDep != nil {
+		deps = append(deps, *tableDep)
+	}
+
+	title := ""
+	if name != "" {
+		title = "Crate `" + name + "`"
+		if version != "" {
+			title += " " + version
+		}
+	}
+	return title, deps, scanner.Err()
+}
+
+// cargoVersion extracts the version of a dependency given as a string or
+// an inline table
+func cargoVersion(value string) string {
+	if !strings.HasPrefix(value, "{") {
+		return strings.Trim(value, `"'`)
+	}
+	for _, part := range strings.Split(strings.Trim(value, "{} "), ",") {
+		if key, v, ok := strings.Cut(part, "="); ok && strings.TrimSpace(key) == "version" {
+			return strings.Trim(strings.TrimSpace(v), `"'`)
+		}
+	}
+	return ""
+}
+
+// parseRequirements reads the requirement specifiers of requirements.txt
+func parseRequirements(content string) (string, []dependency, error) {
+	var deps []dependency
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "-") {
+			deps = append(deps, dependency{Name: line, Kind: "option"})
+			continue
+		}
+		// Environment markers follow a semicolon
+		spec, _, _ := strings.Cut(line, ";")
+		spec = strings.TrimSpace(spec)
+		i := strings.IndexAny(spec, "=<>!~ [")
+		if i < 0 {
+			deps = append(deps, dependency{Name: spec, Kind: "runtime"})
+			continue
+		}
+		deps = append(deps, dependency{Name: spec[:i], Version: strings.TrimSpace(spec[i:]), Kind: "runtime"})
+	}
+	return "", deps, scanner.Err()
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tnypxl/rollup/internal/config"
+)
+
+func TestManifestParsers(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantTitle string
+		wantDeps  []dependency
+	}{
+		{
+			name: "go.mod",
+			content: "module example.com/app // the app\n\ngo 1.23\n\n" +
+				"require github.com/spf13/cobra v1.8.0\n\n" +
+				"require (\n\tgolang.org/x/net v0.20.0\n\tgopkg.in/yaml.v2 v2.4.0 // indirect\n)\n",
+			wantTitle: "Module `example.com/app`, Go 1.23",
+			wantDeps: []dependency{
+				{"github.com/spf13/cobra", "v1.8.0", "direct"},
+				{"golang.org/x/net", "v0.20.0", "direct"},
+				{"gopkg.in/yaml.v2", "v2.4.0", "indirect"},
+			},
+		},
+		{
+			name: "package.json",
+			content: `{"name": "web", "version": "1.2.0",
+				"dependencies": {"react": "^18.2.0", "axios": "^1.6.0"},
+				"devDependencies": {"vite": "^5.0.0"}}`,
+			wantTitle: "Package `web` 1.2.0",
+			wantDeps: []dependency{
+				{"axios", "^1.6.0", "runtime"},
+				{"react", "^18.2.0", "runtime"},
+				{"vite", "^5.0.0", "dev"},
+			},
+		},
+		{
+			name: "Cargo.toml",
+			content: "[package]\nname = \"tool\"\nversion = \"0.3.1\"\n\n" +
+				"[dependencies]\nserde = { version = \"1.0\", features = [\"derive\"] }\nanyhow = \"1\"\n\n" +
+				"[dependencies.tokio]\nversion = \"1.35\"\nfeatures = [\"full\"]\n\n" +
+				"[dev-dependencies]\n# for tests\ntempfile = \"3\"\n",
+			wantTitle: "Crate `tool` 0.3.1",
+			wantDeps: []dependency{
+				{"serde", "1.0", "runtime"},
+				{"anyhow", "1", "runtime"},
+				{"tokio", "1.35", "runtime"},
+				{"tempfile", "3", "dev"},
+			},
+		},
+		{
+			name:    "requirements.txt",
+			content: "# pinned\nrequests==2.31.0\nflask>=3.0 ; python_version >= \"3.8\"\nnumpy\n-r dev.txt\n",
+			wantDeps: []dependency{
+				{"requests", "==2.31.0", "runtime"},
+				{"flask", ">=3.0", "runtime"},
+				{"numpy", "", "runtime"},
+				{"-r dev.txt", "", "option"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, deps, err := manifestParsers[tt.name](tt.content)
+			if err != nil {
+				t.Fatalf("parser returned error: %v", err)
+			}
+			if title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", title, tt.wantTitle)
+			}
+			if !reflect.DeepEqual(deps, tt.wantDeps) {
+				t.Errorf("dependencies = %+v, want %+v", deps, tt.wantDeps)
+			}
+		})
+	}
+}
+
+func TestManifestTable(t *testing.T) {
+	got := manifestTable("Module `m`", []dependency{{"a", "v1", "direct"}, {"b", "", "runtime"}})
+	want := "Module `m`\n\n" +
+		"| Dependency | Version | Kind |\n" +
+		"|------------|---------|------|\n" +
+		"| `a` | v1 | direct |\n" +
+		"| `b` | - | runtime |\n"
+	if got != want {
+		t.Errorf("manifestTable() = %q, want %q", got, want)
+	}
+
+	if got := manifestTable("", nil); got != "No dependencies.\n" {
+		t.Errorf("manifestTable() without dependencies = %q", got)
+	}
+}
+
+func TestLockfileEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+	}{
+		{"go.sum", "a v1 h1:x=\na v1/go.mod h1:y=\n\nb v2 h1:z=\n", 3},
+		{"package-lock.json", `{"packages": {"": {}, "node_modules/a": {}, "node_modules/b": {}}}`, 2},
+		{"package-lock.json", `{"dependencies": {"a": {}}}`, 1},
+		{"yarn.lock", "# yarn lockfile v1\n\n\"a@^1.0.0\":\n  version \"1.0.0\"\n\nb@^2.0.0:\n  version \"2.0.0\"\n", 2},
+		{"Cargo.lock", "version = 3\n\n[[package]]\nname = \"a\"\n\n[[package]]\nname = \"b\"\n", 2},
+		{"composer.lock", `{"packages": [{}, {}], "packages-dev": [{}]}`, 3},
+	}
+
+	for _, tt := range tests {
+		if got := lockfileEntries(tt.name, tt.content); got != tt.want {
+			t.Errorf("lockfileEntries(%s) = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	summary := lockfileSummary("go.sum", "a v1 h1:x=\n")
+	if !strings.HasPrefix(summary, "Lockfile with 1 entries, 11 B, SHA-256 `") {
+		t.Errorf("lockfileSummary() = %q", summary)
+	}
+}
+
+func TestResolveHandlers(t *testing.T) {
+	handlers, err := resolveHandlers(map[string]string{"go.sum": handlerRaw, "uv.lock": handlerSummary}, false)
+	if err != nil {
+		t.Fatalf("resolveHandlers() returned error: %v", err)
+	}
+	for name, want := range map[string]string{"go.mod": handlerManifest, "go.sum": handlerRaw, "uv.lock": handlerSummary} {
+		if handlers[name] != want {
+			t.Errorf("handler for %s = %q, want %q", name, handlers[name], want)
+		}
+	}
+
+	handlers, err = resolveHandlers(map[string]string{"uv.lock": handlerSummary}, true)
+	if err != nil {
+		t.Fatalf("resolveHandlers() returned error: %v", err)
+	}
+	if handlers["go.mod"] != "" || handlers["uv.lock"] != handlerSummary {
+		t.Errorf("resolveHandlers() with raw = %v, want manifests left as they are", handlers)
+	}
+
+	for _, configured := range []map[string]string{{"pom.xml": handlerManifest}, {"go.sum": "hide"}} {
+		if _, err := resolveHandlers(configured, false); err == nil {
+			t.Errorf("resolveHandlers(%v) returned no error", configured)
+		}
+	}
+	if _, err := resolveOptions(&config.Config{FileExtensions: []string{"go"}, FileHandlers: map[string]string{"go.sum": "hide"}}); err == nil {
+		t.Errorf("resolveOptions() expected an error for an unknown file handler")
+	}
+}
+
+func TestHandlerFor(t *testing.T) {
+	handlers := map[string]string{"go.sum": handlerSummary, "ipynb": handlerNotebook, "special.ipynb": handlerRaw}
+	tests := map[string]string{
+		"go.sum":         handlerSummary,
+		"Analysis.IPYNB": handlerNotebook,
+		"special.ipynb":  handlerRaw,
+		"main.go":        "",
+		"Makefile":       "",
+	}
+	for name, want := range tests {
+		if got := handlerFor(handlers, name); got != want {
+			t.Errorf("handlerFor(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestHandleFileFallsBackOnInvalidManifest(t *testing.T) {
+	if got := handleFile("package.json", handlerManifest, "{not json", &rollupOptions{}); got != "" {
+		t.Errorf("handleFile() = %q, want the file kept as is", got)
+	}
+}
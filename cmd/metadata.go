@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+)
+
+// metadataFields lists the per-file metadata that can be requested with
+// file_metadata, in the order they are rendered
+var metadataFields = []string{"size", "lines", "tokens", "sha256", "mtime", "git"}
+
+// validateMetadataFields checks requested metadata against metadataFields
+func validateMetadataFields(fields []string) error {
+	for _, field := range fields {
+		if !slices.Contains(metadataFields, field) {
+			return fmt.Errorf("unknown file metadata %q: must be one of %s", field, strings.Join(metadataFields, ", "))
+		}
+	}
+	return nil
+}
+
+// estimateTokens approximates the number of model tokens in content using
+// the common rule of thumb of four bytes per token
+func estimateTokens(content string) int {
+	return (len(content) + 3) / 4
+}
+
+// metadataItem is one named piece of per-file metadata
+type metadataItem struct {
+	Name  string
+	Value string
+}
+
+// fileMetadata collects the requested metadata for one file
+func fileMetadata(fields []string, rootDir, filePath string, info os.FileInfo, content []byte) []metadataItem {
+	var items []metadataItem
+	for _, field := range metadataFields {
+		if !slices.Contains(fields, field) {
+			continue
+		}
+		switch field {
+		case "size":
+			items = append(items, metadataItem{"Size", humanReadableSize(info.Size())})
+		case "lines":
+			items = append(items, metadataItem{"Lines", fmt.Sprint(countLines(string(content)))})
+		case "tokens":
+			items = append(items, metadataItem{"Tokens", fmt.Sprintf("~%d", estimateTokens(string(content)))})
+		case "sha256":
+			sum := sha256.Sum256(content)
+			items = append(items, metadataItem{"SHA-256", "`" + hex.EncodeToString(sum[:]) + "`"})
+		case "mtime":
+			items = append(items, metadataItem{"Modified", info.ModTime().Format(time.RFC3339)})
+		case "git":
+			commit, author := lastCommit(rootDir, filePath)
+			items = append(items, metadataItem{"Last commit", commit}, metadataItem{"Author", author})
+		}
+	}
+	return items
+}
+
+// metadataTable renders metadata as a one-row Markdown table, or returns ""
+// when there is none
+func metadataTable(items []metadataItem) string {
+	if len(items) == 0 {
+		return ""
+	}
+
+	names := make([]string, len(items))
+	separators := make([]string, len(items))
+	values := make([]string, len(items))
+	for i, item := range items {
+		names[i] = item.Name
+		separators[i] = strings.Repeat("-", len(item.Name))
+		values[i] = item.Value
+	}
+	return "| " + strings.Join(names, " | ") + " |\n" +
+		"|-" + strings.Join(separators, "-|-") + "-|\n" +
+		"| " + strings.Join(values, " | ") + " |\n"
+}
+
+// lastCommit returns the short hash and date, and the author, of the last
+// commit touching filePath, or "-" for both when git has no answer
+func lastCommit(rootDir, filePath string) (string, string) {
+	out, err := exec.Command("git", "-C", rootDir, "log", "-1", "--format=%h %as%x00%an", "--", filePath).Output()
+	if err != nil {
+		return "-", "-"
+	}
+	commit, author, ok := strings.Cut(strings.TrimSpace(string(out)), "\x00")
+	if !ok || commit == "" {
+		return "-", "-"
+	}
+	return commit, author
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tnypxl/rollup/internal/config"
+)
+
+func TestValidateMetadataFields(t *testing.T) {
+	if err := validateMetadataFields([]string{"size", "lines", "tokens", "sha256", "mtime", "git"}); err != nil {
+		t.Errorf("validateMetadataFields() unexpected error: %v", err)
+	}
+	if err := validateMetadataFields([]string{"size", "owner"}); err == nil {
+		t.Errorf("validateMetadataFields() expected an error for an unknown field")
+	}
+
+	// Settings from the config file are checked here too, not by the config
+	// package
+	if _, err := resolveOptions(&config.Config{FileExtensions: []string{"go"}, FileMetadata: []string{"owner"}}); err == nil {
+		t.Errorf("resolveOptions() expected an error for unknown file_metadata")
+	}
+}
+
+func TestFileMetadata(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "main.go")
+	content := []byte("package main\n\nfunc main() {}\n")
+	if err := os.WriteFile(filePath, content, 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+
+	if result := metadataTable(fileMetadata(nil, tempDir, filePath, info, content)); result != "" {
+		t.Errorf("fileMetadata() with no fields = %q; want empty", result)
+	}
+
+	result := metadataTable(fileMetadata([]string{"sha256", "lines", "size", "tokens"}, tempDir, filePath, info, content))
+	expected := "| Size | Lines | Tokens | SHA-256 |\n" +
+		"|------|-------|--------|---------|\n" +
+		"| 29 B | 3 | ~8 | `55a60bb97151b2b4b680462447ce60ec34511b14fa10d77440c97b9777101566` |\n"
+	if result != expected {
+		t.Errorf("fileMetadata() = %q; want %q", result, expected)
+	}
+
+	result = metadataTable(fileMetadata([]string{"git"}, tempDir, filePath, info, content))
+	if !strings.Contains(result, "| Last commit | Author |") || !strings.Contains(result, "| - | - |") {
+		t.Errorf("fileMetadata() outside a git repository = %q", result)
+	}
+}
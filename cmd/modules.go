@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// reasonOtherModule excludes files of modules not selected with --module
+const reasonOtherModule = "other module"
+
+// moduleResolver finds the module of each directory, caching the answer
+type moduleResolver struct {
+	dirs map[string]*goModule
+}
+
+func newModuleResolver() *moduleResolver {
+	return &moduleResolver{dirs: make(map[string]*goModule)}
+}
+
+// moduleFor returns the module holding the absolute directory dir, or nil
+// when no go.mod is found above it
+func (r *moduleResolver) moduleFor(dir string) *goModule {
+	if mod, ok := r.dirs[dir]; ok {
+		return mod
+	}
+	var mod *goModule
+	if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+		if modPath, err := readModulePath(filepath.Join(dir, "go.mod")); err == nil {
+			mod = &goModule{root: dir, path: modPath}
+		}
+	} else if parent := filepath.Dir(dir); parent != dir {
+		mod = r.moduleFor(parent)
+	}
+	r.dirs[dir] = mod
+	return mod
+}
+
+// findWorkspace returns the path of the go.work file governing dir, or ""
+// when there is none
+func findWorkspace(dir string) string {
+	for d := dir; ; d = filepath.Dir(d) {
+		goWork := filepath.Join(d, "go.work")
+		if _, err := os.Stat(goWork); err == nil {
+			return goWork
+		}
+		if filepath.Dir(d) == d {
+			return ""
+		}
+	}
+}
+
+// readWorkspace returns the modules listed in the use directives of a
+// go.work file
+func readWorkspace(goWork string) ([]*goModule, error) {
+	f, err := os.Open(goWork)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var dirs []string
+	inBlock := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+		case inBlock && line != "":
+			dirs = append(dirs, line)
+		case line == "use (":
+			inBlock = true
+		case strings.HasPrefix(line, "use ") || strings.HasPrefix(line, "use\t"):
+			dirs = append(dirs, strings.TrimSpace(line[len("use"):]))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var modules []*goModule
+	for _, dir := range dirs {
+		if unquoted, err := strconv.Unquote(dir); err == nil {
+			dir = unquoted
+		}
+		root := filepath.Join(filepath.Dir(goWork), filepath.FromSlash(dir))
+		modPath, err := readModulePath(filepath.Join(root, "go.mod"))
+		if err != nil {
+			return nil, fmt.Errorf("%s uses %s: %v", goWork, dir, err)
+		}
+		modules = append(modules, &goModule{root: root, path: modPath})
+	}
+	return modules, nil
+}
+
+// validateModuleFilter checks that each --module pattern names a module of
+// the workspace governing root, when there is one
+func validateModuleFilter(root string, patterns []string) error {
+	goWork := findWorkspace(root)
+	if goWork == "" || len(patterns) == 0 {
+		return nil
+	}
+	modules, err := readWorkspace(goWork)
+	if err != nil {
+		return err
+	}
+	for _, pattern := range patterns {
+		found := false
+		for _, mod := range modules {
+			if matchModule(pattern, mod.path) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("no module in %s matches %q", goWork, pattern)
+		}
+	}
+	return nil
+}
+
+// checkModuleFilter checks that each --module pattern matches the module of
+// at least one candidate file, so a mistyped module is not mistaken for an
+// empty one. Without a go.work, this is the only check.
+func checkModuleFilter(root string, patterns []string, entries []*fileEntry) error {
+	found := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.module != "" {
+			found[entry.module] = true
+		}
+	}
+	for _, pattern := range patterns {
+		matched := false
+		for modPath := range found {
+			if matchModule(pattern, modPath) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("no module under %s matches %q", root, pattern)
+		}
+	}
+	return nil
+}
+
+// matchModule reports whether a module path matches a --module pattern:
+// the module path itself, or a prefix followed by /... for every module
+// below it
+func matchModule(pattern, modPath string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/..."); ok {
+		return modPath == prefix || strings.HasPrefix(modPath, prefix+"/")
+	}
+	return pattern == modPath
+}
+
+// groupByModule keeps the files of each module together when they span
+// more than one module, and marks the first file of each module with a
+// heading. Files outside any module come first; modules follow in the
+// order their first file appears.
+func groupByModule(files []*fileSection) []*fileSection {
+	rank := make(map[string]int)
+	for _, f := range files {
+		if _, ok := rank[f.Module]; !ok {
+			rank[f.Module] = len(rank) + 1
+		}
+	}
+	delete(rank, "")
+	if len(rank) < 2 {
+		return files
+	}
+
+	grouped := append([]*fileSection(nil), files...)
+	sort.SliceStable(grouped, func(i, j int) bool {
+		return rank[grouped[i].Module] < rank[grouped[j].Module]
+	})
+	for i, f := range grouped {
+		if f.Module != "" && (i == 0 || grouped[i-1].Module != f.Module) {
+			f.ModuleHeading = f.Module
+		}
+	}
+	return grouped
+}
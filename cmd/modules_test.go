@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tnypxl/rollup/internal/config"
+)
+
+func TestWorkspaceModules(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"go.work":          "go 1.23\n\nuse (\n\t./api\n\t\"./web/ui\" // quoted\n)\n\nuse ./tools\n",
+		"api/go.mod":       "module example.com/api\n",
+		"api/v2/client.go": "package v2\n",
+		"web/ui/go.mod":    "module example.com/web/ui\n",
+		"tools/go.mod":     "module example.com/tools\n",
+		"README.md":        "# Workspace\n",
+	})
+
+	goWork := findWorkspace(filepath.Join(root, "api", "v2"))
+	if goWork != filepath.Join(root, "go.work") {
+		t.Fatalf("findWorkspace() = %q", goWork)
+	}
+	modules, err := readWorkspace(goWork)
+	if err != nil {
+		t.Fatalf("readWorkspace() returned error: %v", err)
+	}
+	var paths []string
+	for _, mod := range modules {
+		paths = append(paths, mod.path)
+	}
+	if want := []string{"example.com/api", "example.com/web/ui", "example.com/tools"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("readWorkspace() = %v, want %v", paths, want)
+	}
+
+	r := newModuleResolver()
+	if mod := r.moduleFor(filepath.Join(root, "api", "v2")); mod == nil || mod.path != "example.com/api" {
+		t.Errorf("moduleFor(api/v2) = %+v", mod)
+	}
+	if mod := r.moduleFor(filepath.Join(root, "web")); mod != nil {
+		t.Errorf("moduleFor(web) = %+v, want nil", mod)
+	}
+
+	if err := validateModuleFilter(root, []string{"example.com/web/..."}); err != nil {
+		t.Errorf("validateModuleFilter() returned error: %v", err)
+	}
+	if err := validateModuleFilter(root, []string{"example.com/missing"}); err == nil {
+		t.Errorf("validateModuleFilter() accepted a module outside the workspace")
+	}
+}
+
+func TestMatchModule(t *testing.T) {
+	tests := []struct {
+		pattern, modPath string
+		want             bool
+	}{
+		{"example.com/api", "example.com/api", true},
+		{"example.com/api", "example.com/api/v2", false},
+		{"example.com/api/...", "example.com/api", true},
+		{"example.com/api/...", "example.com/api/v2", true},
+		{"example.com/api/...", "example.com/apiserver", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchModule(tt.pattern, tt.modPath); got != tt.want {
+			t.Errorf("matchModule(%q, %q) = %v, want %v", tt.pattern, tt.modPath, got, tt.want)
+		}
+	}
+}
+
+func TestGroupByModule(t *testing.T) {
+	files := []*fileSection{
+		{Path: "api/api.go", Module: "example.com/api"},
+		{Path: "README.md"},
+		{Path: "tools/t.go", Module: "example.com/tools"},
+		{Path: "api/v2/client.go", Module: "example.com/api"},
+	}
+
+	var got, headings []string
+	for _, f := range groupByModule(files) {
+		got = append(got, f.Path)
+		if f.ModuleHeading != "" {
+			headings = append(headings, f.Path+" "+f.ModuleHeading)
+		}
+	}
+	if want := []string{"README.md", "api/api.go", "api/v2/client.go", "tools/t.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("groupByModule() = %v, want %v", got, want)
+	}
+	if want := []string{"api/api.go example.com/api", "tools/t.go example.com/tools"}; !reflect.DeepEqual(headings, want) {
+		t.Errorf("headings = %v, want %v", headings, want)
+	}
+
+	single := []*fileSection{{Path: "a.go", Module: "example.com/a"}, {Path: "README.md"}}
+	for _, f := range groupByModule(single) {
+		if f.ModuleHeading != "" {
+			t.Errorf("single-module rollup got heading on %s", f.Path)
+		}
+	}
+
+	// Module headings do not get in the way of unrolling
+	parsed, err := parseRollup("# Module: example.com/api\n\n# File: api/api.go\n\n```go\npackage api\n```\n\n# Module: example.com/tools\n\n# File: tools/t.go\n\n```go\npackage tools\n```\n")
+	if err != nil || len(parsed) != 2 {
+		t.Errorf("parseRollup() = %v, %v", parsed, err)
+	}
+}
+
+func TestRunRollupModulesWithTestsAtEnd(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTree(t, tempDir, map[string]string{
+		"go.work":            "go 1.23\n\nuse (\n\t./api\n\t./tools\n)\n",
+		"api/go.mod":         "module example.com/api\n",
+		"api/api.go":         "package api\n",
+		"api/api_test.go":    "package api\n",
+		"tools/go.mod":       "module example.com/tools\n",
+		"tools/tool.go":      "package tools\n",
+		"tools/tool_test.go": "package tools\n",
+	})
+
+	originalWd, _ := os.Getwd()
+	os.Chdir(tempDir)
+	defer os.Chdir(originalWd)
+
+	cfg = &config.Config{FileExtensions: []string{"go"}, Tests: testsEnd}
+	if err := runRollup(cfg); err != nil {
+		t.Fatalf("runRollup() failed: %v", err)
+	}
+	outputFiles, _ := filepath.Glob("*.rollup.md")
+	if len(outputFiles) != 1 {
+		t.Fatalf("Expected one rollup file, found %v", outputFiles)
+	}
+	data, err := os.ReadFile(outputFiles[0])
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+
+	// Each module's tests come at the end of that module, under its heading
+	var got []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "# Module: ") || strings.HasPrefix(line, "# File: ") {
+			got = append(got, line)
+		}
+	}
+	want := []string{
+		"# Module: example.com/api",
+		"# File: api/api.go",
+		"# File: api/api_test.go",
+		"# Module: example.com/tools",
+		"# File: tools/tool.go",
+		"# File: tools/tool_test.go",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("headings = %q, want %q", got, want)
+	}
+}
+
+func TestRunRollupModuleFilterWithoutWorkspace(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTree(t, tempDir, map[string]string{
+		"go.mod":        "module example.com/app\n",
+		"main.go":       "package main\n",
+		"tools/go.mod":  "module example.com/app/tools\n",
+		"tools/tool.go": "package tools\n",
+	})
+
+	originalWd, _ := os.Getwd()
+	os.Chdir(tempDir)
+	defer os.Chdir(originalWd)
+
+	// Without a go.work, patterns are checked against the modules found
+	cfg = &config.Config{FileExtensions: []string{"go"}, Modules: []string{"example.com/app/tool"}}
+	err := runRollup(cfg)
+	if err == nil || !strings.Contains(err.Error(), `"example.com/app/tool"`) {
+		t.Errorf("runRollup() with a mistyped module: error = %v", err)
+	}
+	if outputFiles, _ := filepath.Glob("*.rollup.md"); len(outputFiles) != 0 {
+		t.Errorf("runRollup() with a mistyped module wrote %v", outputFiles)
+	}
+
+	cfg = &config.Config{FileExtensions: []string{"go"}, Modules: []string{"example.com/app/tools"}}
+	if err := runRollup(cfg); err != nil {
+		t.Fatalf("runRollup() failed: %v", err)
+	}
+	outputFiles, _ := filepath.Glob("*.rollup.md")
+	if len(outputFiles) != 1 {
+		t.Fatalf("Expected one rollup file, found %v", outputFiles)
+	}
+	data, err := os.ReadFile(outputFiles[0])
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	if content := string(data); !strings.Contains(content, "# File: tools/tool.go") || strings.Contains(content, "# File: main.go") {
+		t.Errorf("Output file does not hold only the tools module:\n%s", content)
+	}
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// notebookOutputLines is the most lines of a text output shown for a
+// notebook cell
+const notebookOutputLines = 20
+
+// notebook is the part of a Jupyter notebook (nbformat 4) rollup reads
+type notebook struct {
+	Cells    []notebookCell `json:"cells"`
+	Metadata struct {
+		KernelSpec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+}
+
+type notebookCell struct {
+	CellType string           `json:"cell_type"`
+	Source   notebookText     `json:"source"`
+	Outputs  []notebookOutput `json:"outputs"`
+}
+
+type notebookOutput struct {
+	OutputType string                  `json:"output_type"`
+	Text       notebookText            `json:"text"`
+	Data       map[string]notebookText `json:"data"`
+	EName      string                  `json:"ename"`
+	EValue     string                  `json:"evalue"`
+}
+
+// notebookText is a multiline string, stored either as one string or as a
+// list of lines
+type notebookText string
+
+func (t *notebookText) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*t = notebookText(strings.Join(lines, ""))
+		return nil
+	}
+	// Image data and other non-text values are only checked for
+	// presence, so anything that is not a string counts as empty
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = notebookText(s)
+	}
+	return nil
+}
+
+// convertNotebook renders a notebook as Markdown: markdown cells as they
+// are, code cells fenced in the kernel's language and raw cells fenced
+// without one. With outputs, each code cell is followed by its outputs,
+// text cut to notebookOutputLines and images replaced by a placeholder.
+func convertNotebook(content string, outputs bool) (string, error) {
+	var nb notebook
+	if err := json.Unmarshal([]byte(content), &nb); err != nil {
+		return "", err
+	}
+	if nb.Cells == nil {
+		return "", fmt.Errorf("no cells")
+	}
+
+	lang := strings.ToLower(nb.Metadata.LanguageInfo.Name)
+	if lang == "" {
+		lang = strings.ToLower(nb.Metadata.KernelSpec.Language)
+	}
+
+	var parts []string
+	for _, cell := range nb.Cells {
+		source := string(cell.Source)
+		switch cell.CellType {
+		case "markdown":
+			if strings.TrimSpace(source) != "" {
+				parts = append(parts, strings.TrimRight(source, "\n")+"\n")
+			}
+		case "code":
+			part := fencedBlock(source, lang)
+			if outputs {
+				for _, out := range cell.Outputs {
+					part += notebookOutputText(out)
+				}
+			}
+			parts = append(parts, part)
+		default:
+			parts = append(parts, fencedBlock(source, ""))
+		}
+	}
+	return strings.Join(parts, "\n"), nil
+}
+
+// notebookOutputText renders one output of a code cell, or returns "" for
+// outputs with nothing to show
+func notebookOutputText(out notebookOutput) string {
+	switch out.OutputType {
+	case "stream":
+		return "\nOutput:\n\n" + fencedBlock(truncateOutput(string(out.Text)), "text")
+	case "error":
+		return "\nError:\n\n" + fencedBlock(out.EName+": "+out.EValue, "text")
+	}
+
+	var images []string
+	for mime := range out.Data {
+		if strings.HasPrefix(mime, "image/") {
+			images = append(images, mime)
+		}
+	}
+	if len(images) > 0 {
+		sort.Strings(images)
+		return "\n[Image output: " + strings.Join(images, ", ") + "]\n"
+	}
+	if text, ok := out.Data["text/plain"]; ok {
+		return "\nOutput:\n\n" + fencedBlock(truncateOutput(string(text)), "text")
+	}
+	if _, ok := out.Data["text/html"]; ok {
+		return "\n[HTML output]\n"
+	}
+	return ""
+}
+
+// truncateOutput keeps the first notebookOutputLines lines of text and
+// notes how many were cut
+func truncateOutput(text string) string {
+	lines := splitLines(text)
+	if len(lines) <= notebookOutputLines {
+		return text
+	}
+	kept := strings.Join(lines[:notebookOutputLines], "")
+	return kept + fmt.Sprintf("... (%d more lines)\n", len(lines)-notebookOutputLines)
+}
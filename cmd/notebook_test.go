@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+const testNotebook = `{
+  "cells": [
+    {"cell_type": "markdown", "metadata": {}, "source": ["# Analysis\n", "\n", "Load the data."]},
+    {"cell_type": "code", "execution_count": 1, "metadata": {}, "source": "import pandas as pd\ndf = pd.read_csv('data.csv')",
+     "outputs": [{"output_type": "stream", "name": "stdout", "text": ["loaded\n"]}]},
+    {"cell_type": "code", "execution_count": 2, "metadata": {}, "source": ["df.plot()"],
+     "outputs": [
+       {"output_type": "display_data", "data": {"image/png": "iVBORw0KGgo=", "text/plain": ["<Figure>"]}, "metadata": {}},
+       {"output_type": "execute_result", "data": {"text/plain": "42"}, "metadata": {}, "execution_count": 2},
+       {"output_type": "error", "ename": "KeyError", "evalue": "'x'", "traceback": []}
+     ]},
+    {"cell_type": "raw", "metadata": {}, "source": "raw text"}
+  ],
+  "metadata": {"kernelspec": {"language": "python", "name": "python3"}, "language_info": {"name": "python"}},
+  "nbformat": 4,
+  "nbformat_minor": 5
+}`
+
+func TestConvertNotebook(t *testing.T) {
+	got, err := convertNotebook(testNotebook, false)
+	if err != nil {
+		t.Fatalf("convertNotebook() returned error: %v", err)
+	}
+	want := "# Analysis\n\nLoad the data.\n" +
+		"\n```python\nimport pandas as pd\ndf = pd.read_csv('data.csv')\n```\n" +
+		"\n```python\ndf.plot()\n```\n" +
+		"\n```\nraw text\n```\n"
+	if got != want {
+		t.Errorf("convertNotebook() = %q, want %q", got, want)
+	}
+
+	got, err = convertNotebook(testNotebook, true)
+	if err != nil {
+		t.Fatalf("convertNotebook() with outputs returned error: %v", err)
+	}
+	for _, part := range []string{
+		"```\n\nOutput:\n\n```text\nloaded\n```\n",
+		"\n[Image output: image/png]\n",
+		"\nOutput:\n\n```text\n42\n```\n",
+		"\nError:\n\n```text\nKeyError: 'x'\n```\n",
+	} {
+		if !strings.Contains(got, part) {
+			t.Errorf("convertNotebook() with outputs = %q, want it to contain %q", got, part)
+		}
+	}
+	if strings.Contains(got, "<Figure>") {
+		t.Errorf("convertNotebook() showed the text of an image output")
+	}
+
+	for _, content := range []string{"{not json", `{"nbformat": 4}`} {
+		if _, err := convertNotebook(content, false); err == nil {
+			t.Errorf("convertNotebook(%q) returned no error", content)
+		}
+	}
+}
+
+func TestTruncateOutput(t *testing.T) {
+	short := "a\nb\n"
+	if got := truncateOutput(short); got != short {
+		t.Errorf("truncateOutput() = %q, want %q", got, short)
+	}
+
+	long := strings.Repeat("line\n", notebookOutputLines+5)
+	want := strings.Repeat("line\n", notebookOutputLines) + "... (5 more lines)\n"
+	if got := truncateOutput(long); got != want {
+		t.Errorf("truncateOutput() = %q, want %q", got, want)
+	}
+}
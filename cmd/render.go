@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// codeFence returns a backtick fence longer than the longest run of
+// backticks in content, so nothing in the file can close the block early
+func codeFence(content string) string {
+	longest, run := 0, 0
+	for i := 0; i < len(content); i++ {
+		if content[i] == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
+
+// fencedBlock wraps content in a fenced code block tagged with lang. The
+// closing fence always starts on its own line, even when content does not
+// end with a newline.
+func fencedBlock(content, lang string) string {
+	fence := codeFence(content)
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return fence + lang + "\n" + content + fence + "\n"
+}
+
+// countLines returns the number of lines in content, counting a final line
+// without a trailing newline
+func countLines(content string) int {
+	n := strings.Count(content, "\n")
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		n++
+	}
+	return n
+}
+
+// numberLines prefixes each line of content with its 1-based line number,
+// padded to the width of the largest number
+func numberLines(content string) string {
+	total := countLines(content)
+	if total == 0 {
+		return content
+	}
+	width := len(strconv.Itoa(total))
+
+	lines := strings.SplitAfter(content, "\n")
+	var b strings.Builder
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		if line == "\n" {
+			fmt.Fprintf(&b, "%*d |\n", width, i+1)
+			continue
+		}
+		fmt.Fprintf(&b, "%*d | %s", width, i+1, line)
+	}
+	return b.String()
+}
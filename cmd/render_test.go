@@ -0,0 +1,83 @@
+package cmd
+
+import "testing"
+
+func TestCodeFence(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{"package main\n", "```"},
+		{"use `code` inline\n", "```"},
+		{"``double``\n", "```"},
+		{"```go\nfmt.Println()\n```\n", "````"},
+		{"`````\n", "``````"},
+		{"", "```"},
+	}
+
+	for _, test := range tests {
+		result := codeFence(test.content)
+		if result != test.expected {
+			t.Errorf("codeFence(%q) = %q; want %q", test.content, result, test.expected)
+		}
+	}
+}
+
+func TestFencedBlock(t *testing.T) {
+	tests := []struct {
+		content  string
+		lang     string
+		expected string
+	}{
+		{"package main\n", "go", "```go\npackage main\n```\n"},
+		{"no trailing newline", "txt", "```txt\nno trailing newline\n```\n"},
+		{"# Doc\n\n```sh\nls\n```\n", "md", "````md\n# Doc\n\n```sh\nls\n```\n````\n"},
+		{"", "txt", "```txt\n```\n"},
+	}
+
+	for _, test := range tests {
+		result := fencedBlock(test.content, test.lang)
+		if result != test.expected {
+			t.Errorf("fencedBlock(%q, %q) = %q; want %q", test.content, test.lang, result, test.expected)
+		}
+	}
+}
+
+func TestCountLines(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected int
+	}{
+		{"", 0},
+		{"one", 1},
+		{"one\n", 1},
+		{"one\ntwo", 2},
+		{"one\n\n", 2},
+	}
+
+	for _, test := range tests {
+		result := countLines(test.content)
+		if result != test.expected {
+			t.Errorf("countLines(%q) = %d; want %d", test.content, result, test.expected)
+		}
+	}
+}
+
+func TestNumberLines(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{"", ""},
+		{"package main\n", "1 | package main\n"},
+		{"a\n\nb", "1 | a\n2 |\n3 | b"},
+		{"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n", " 1 | 1\n 2 | 2\n 3 | 3\n 4 | 4\n 5 | 5\n 6 | 6\n 7 | 7\n 8 | 8\n 9 | 9\n10 | 10\n"},
+	}
+
+	for _, test := range tests {
+		result := numberLines(test.content)
+		if result != test.expected {
+			t.Errorf("numberLines(%q) = %q; want %q", test.content, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Roles a file can play in a project
+const (
+	roleSource  = "source"
+	roleTest    = "test"
+	roleDocs    = "docs"
+	roleConfig  = "config"
+	roleBuild   = "build"
+	roleFixture = "fixture"
+)
+
+// fileRoles lists every role, in the order they are documented
+var fileRoles = []string{roleSource, roleTest, roleDocs, roleConfig, roleBuild, roleFixture}
+
+// Placements of test files with tests:
+const (
+	testsAdjacent = "adjacent"
+	testsEnd      = "end"
+	testsOmit     = "omit"
+)
+
+// Reasons files are excluded by role
+const (
+	reasonRole        = "role"
+	reasonTestOmitted = "test omitted"
+)
+
+var (
+	fixtureDirs = []string{"testdata", "fixtures", "__fixtures__", "__snapshots__"}
+	testDirs    = []string{"test", "tests", "__tests__", "spec"}
+	docsDirs    = []string{"doc", "docs"}
+
+	buildFiles = []string{
+		"makefile", "gnumakefile", "dockerfile", "containerfile", "justfile", "jenkinsfile",
+		"go.mod", "go.sum", "go.work", "go.work.sum", "cargo.toml", "cargo.lock",
+		"build.gradle", "build.gradle.kts", "settings.gradle", "pom.xml", "cmakelists.txt",
+		"package.json", "package-lock.json", "yarn.lock", "pnpm-lock.yaml",
+		"requirements.txt", "setup.py", "pyproject.toml", ".goreleaser.yml", ".goreleaser.yaml",
+		".gitlab-ci.yml", "build.sh",
+	}
+	buildExtensions = []string{"mk", "cmake", "bazel", "bzl"}
+
+	docsFiles      = []string{"readme", "license", "changelog", "contributing", "authors", "notice"}
+	docsExtensions = []string{"md", "markdown", "rst", "adoc", "txt"}
+
+	configExtensions = []string{"yml", "yaml", "toml", "json", "ini", "cfg", "conf", "env", "properties"}
+)
+
+// fileRole classifies a file by the conventions of common ecosystems. The
+// first matching role wins: fixture, test, build, docs, config, source.
+func fileRole(relPath string) string {
+	slashed := filepath.ToSlash(relPath)
+	dirs := strings.Split(slashed, "/")
+	name := strings.ToLower(dirs[len(dirs)-1])
+	dirs = dirs[:len(dirs)-1]
+	ext := strings.TrimPrefix(filepath.Ext(name), ".")
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+
+	inDir := func(names []string) bool {
+		for _, dir := range dirs {
+			if slices.Contains(names, strings.ToLower(dir)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case inDir(fixtureDirs):
+		return roleFixture
+	case testSubject(slashed) != "" || inDir(testDirs):
+		return roleTest
+	case slices.Contains(buildFiles, name) || slices.Contains(buildExtensions, ext) ||
+		strings.HasPrefix(slashed, ".github/workflows/"):
+		return roleBuild
+	case slices.Contains(docsFiles, base) || slices.Contains(docsExtensions, ext) || inDir(docsDirs):
+		return roleDocs
+	case slices.Contains(configExtensions, ext) || (strings.HasPrefix(name, ".") && base == ""):
+		return roleConfig
+	}
+	return roleSource
+}
+
+// testSubject returns the path of the source file a test file is named
+// after, e.g. files.go for files_test.go, or "" for files that are not
+// named as tests
+func testSubject(slashed string) string {
+	dir, name := "", slashed
+	if i := strings.LastIndex(slashed, "/"); i >= 0 {
+		dir, name = slashed[:i+1], slashed[i+1:]
+	}
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	switch {
+	case strings.HasSuffix(base, "_test") && base != "_test":
+		return dir + strings.TrimSuffix(base, "_test") + ext
+	case strings.HasSuffix(base, ".test"), strings.HasSuffix(base, ".spec"):
+		return dir + base[:len(base)-5] + ext
+	case strings.HasPrefix(base, "test_") && ext == ".py":
+		return dir + strings.TrimPrefix(base, "test_") + ext
+	case strings.HasSuffix(base, "Test") && base != "Test" && (ext == ".java" || ext == ".kt"):
+		return dir + strings.TrimSuffix(base, "Test") + ext
+	}
+	return ""
+}
+
+// validateRoles checks requested roles against fileRoles
+func validateRoles(roles []string) error {
+	for _, role := range roles {
+		if !slices.Contains(fileRoles, role) {
+			return fmt.Errorf("unknown role %q: must be one of %s", role, strings.Join(fileRoles, ", "))
+		}
+	}
+	return nil
+}
+
+// arrangeTests places test files according to mode: right after the file
+// they are named after with "adjacent", or after every other file with
+// "end". Other modes keep the order of files.
+func arrangeTests(files []*fileSection, mode string) []*fileSection {
+	if mode != testsAdjacent && mode != testsEnd {
+		return files
+	}
+
+	isTest := func(f *fileSection) bool { return f.Role == roleTest }
+	arranged := make([]*fileSection, 0, len(files))
+	var tests []*fileSection
+	if mode == testsEnd {
+		for _, f := range files {
+			if isTest(f) {
+				tests = append(tests, f)
+			} else {
+				arranged = append(arranged, f)
+			}
+		}
+		return append(arranged, tests...)
+	}
+
+	// Tests whose subject is in the rollup follow it; the rest stay put
+	present := make(map[string]bool)
+	for _, f := range files {
+		present[f.Path] = true
+	}
+	following := make(map[string][]*fileSection)
+	for _, f := range files {
+		if subject := testSubject(f.Path); isTest(f) && subject != "" && present[subject] {
+			following[subject] = append(following[subject], f)
+		}
+	}
+	for _, f := range files {
+		if subject := testSubject(f.Path); isTest(f) && subject != "" && present[subject] {
+			continue
+		}
+		arranged = append(arranged, f)
+		arranged = append(arranged, following[f.Path]...)
+	}
+	return arranged
+}
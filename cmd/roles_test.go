@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tnypxl/rollup/internal/config"
+)
+
+func TestFileRole(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"main.go", roleSource},
+		{"cmd/files.go", roleSource},
+		{"cmd/templates/default.tmpl", roleSource},
+		{"cmd/files_test.go", roleTest},
+		{"web/app.spec.ts", roleTest},
+		{"web/app.test.js", roleTest},
+		{"scripts/test_build.py", roleTest},
+		{"src/FooTest.java", roleTest},
+		{"tests/helpers.py", roleTest},
+		{"cmd/testdata/input.go", roleFixture},
+		{"web/__fixtures__/user.json", roleFixture},
+		{"README.md", roleDocs},
+		{"LICENSE", roleDocs},
+		{"docs/diagram.svg", roleDocs},
+		{"notes.txt", roleDocs},
+		{"rollup.yml", roleConfig},
+		{".editorconfig", roleConfig},
+		{"config/settings.json", roleConfig},
+		{"go.mod", roleBuild},
+		{"Makefile", roleBuild},
+		{"Dockerfile", roleBuild},
+		{".github/workflows/ci.yml", roleBuild},
+		{"package.json", roleBuild},
+	}
+
+	for _, tt := range tests {
+		if got := fileRole(tt.path); got != tt.want {
+			t.Errorf("fileRole(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestTestSubject(t *testing.T) {
+	tests := map[string]string{
+		"cmd/files_test.go":     "cmd/files.go",
+		"web/app.spec.ts":       "web/app.ts",
+		"app.test.js":           "app.js",
+		"scripts/test_build.py": "scripts/build.py",
+		"src/FooTest.java":      "src/Foo.java",
+		"cmd/files.go":          "",
+		"_test.go":              "",
+		"test_build.go":         "",
+	}
+
+	for path, want := range tests {
+		if got := testSubject(path); got != want {
+			t.Errorf("testSubject(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestArrangeTests(t *testing.T) {
+	var files []*fileSection
+	for _, path := range []string{"a.go", "a_test.go", "b_test.go", "c.go", "z/c_test.go", "z/c.go", "orphan_test.go", "README.md"} {
+		files = append(files, &fileSection{Path: path, Role: fileRole(path)})
+	}
+
+	tests := []struct {
+		mode string
+		want []string
+	}{
+		{"", []string{"a.go", "a_test.go", "b_test.go", "c.go", "z/c_test.go", "z/c.go", "orphan_test.go", "README.md"}},
+		{testsAdjacent, []string{"a.go", "a_test.go", "b_test.go", "c.go", "z/c.go", "z/c_test.go", "orphan_test.go", "README.md"}},
+		{testsEnd, []string{"a.go", "c.go", "z/c.go", "README.md", "a_test.go", "b_test.go", "z/c_test.go", "orphan_test.go"}},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, f := range arrangeTests(files, tt.mode) {
+			got = append(got, f.Path)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("arrangeTests(%q) = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestValidateRoles(t *testing.T) {
+	if err := validateRoles(fileRoles); err != nil {
+		t.Errorf("validateRoles() unexpected error: %v", err)
+	}
+	if err := validateRoles([]string{roleSource, "vendor"}); err == nil {
+		t.Errorf("validateRoles() expected an error for an unknown role")
+	}
+	if _, err := resolveOptions(&config.Config{FileExtensions: []string{"go"}, Roles: []string{"vendor"}}); err == nil {
+		t.Errorf("resolveOptions() expected an error for unknown roles")
+	}
+}
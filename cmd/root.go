@@ -52,4 +52,7 @@ func init() {
 	rootCmd.AddCommand(filesCmd)
 	rootCmd.AddCommand(webCmd)
 	rootCmd.AddCommand(generateCmd)
+	rootCmd.AddCommand(explainCmd)
+	rootCmd.AddCommand(unrollCmd)
+	rootCmd.AddCommand(diffCmd)
 }
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tnypxl/rollup/internal/config"
+)
+
+const (
+	// dirIgnoreFile holds extra ignore patterns for the directory it is in
+	dirIgnoreFile = ".rollupignore"
+
+	// dirConfigFile holds a config fragment for the directory it is in
+	dirConfigFile = ".rollup.yml"
+)
+
+// scopedGlob is a set of patterns relative to the directory that declared
+// them. source names the setting or file the patterns came from.
+type scopedGlob struct {
+	dir     string
+	source  string
+	matcher *globMatcher
+}
+
+// ruleSet holds the file selection rules in effect for one directory
+type ruleSet struct {
+	types       []string
+	typesSource string
+	ignore      []scopedGlob
+	codeGen     []scopedGlob
+}
+
+// ruleTree resolves the rules for each directory of a project from the root
+// rules and any .rollupignore and .rollup.yml files found below the root.
+// Nested file_extensions replace the inherited list; nested ignore and
+// code-generated patterns are added after the inherited ones, so a subtree
+// can re-include paths with !pattern.
+type ruleTree struct {
+	root     string
+	foldCase bool
+	dirs     map[string]*ruleSet
+}
+
+func newRuleTree(opts *rollupOptions) *ruleTree {
+	base := &ruleSet{
+		types:       opts.types,
+		typesSource: opts.typesSource,
+		ignore:      []scopedGlob{{source: opts.ignoreSource, matcher: newGlobMatcher(opts.ignore, opts.foldCase)}},
+		codeGen:     []scopedGlob{{source: opts.codeGenSource, matcher: newGlobMatcher(opts.codeGen, opts.foldCase)}},
+	}
+	t := &ruleTree{root: opts.root, foldCase: opts.foldCase, dirs: make(map[string]*ruleSet)}
+	t.dirs[""] = t.load("", base)
+	return t
+}
+
+// rulesFor returns the rules for the directory relDir, relative to the root
+func (t *ruleTree) rulesFor(relDir string) *ruleSet {
+	relDir = filepath.ToSlash(relDir)
+	if relDir == "." {
+		relDir = ""
+	}
+	if rules, ok := t.dirs[relDir]; ok {
+		return rules
+	}
+
+	parentDir := ""
+	if i := strings.LastIndex(relDir, "/"); i >= 0 {
+		parentDir = relDir[:i]
+	}
+	rules := t.load(relDir, t.rulesFor(parentDir))
+	t.dirs[relDir] = rules
+	return rules
+}
+
+// load applies the override files in relDir, if any, on top of parent
+func (t *ruleTree) load(relDir string, parent *ruleSet) *ruleSet {
+	dir := filepath.Join(t.root, filepath.FromSlash(relDir))
+	rules := parent
+
+	derive := func() {
+		if rules == parent {
+			copied := *parent
+			rules = &copied
+		}
+	}
+
+	ignoreSource := filepath.ToSlash(filepath.Join(relDir, dirIgnoreFile))
+	if patterns, err := readIgnoreFile(filepath.Join(dir, dirIgnoreFile)); err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("Warning: Failed to read %s: %v\n", ignoreSource, err)
+		}
+	} else if len(patterns) > 0 {
+		derive()
+		rules.ignore = appendScoped(rules.ignore, scopedGlob{relDir, ignoreSource, newGlobMatcher(patterns, t.foldCase)})
+	}
+
+	fragmentSource := filepath.ToSlash(filepath.Join(relDir, dirConfigFile))
+	fragmentPath := filepath.Join(dir, dirConfigFile)
+	if _, err := os.Stat(fragmentPath); err == nil {
+		fragment, err := config.LoadFragment(fragmentPath)
+		if err != nil {
+			fmt.Printf("Warning: Failed to load %s: %v\n", fragmentSource, err)
+			return rules
+		}
+		derive()
+		if len(fragment.FileExtensions) > 0 {
+			rules.types = fragment.FileExtensions
+			rules.typesSource = "file_extensions in " + fragmentSource
+		}
+		if len(fragment.IgnorePaths) > 0 {
+			rules.ignore = appendScoped(rules.ignore, scopedGlob{relDir, "ignore_paths in " + fragmentSource, newGlobMatcher(fragment.IgnorePaths, t.foldCase)})
+		}
+		if len(fragment.CodeGeneratedPaths) > 0 {
+			rules.codeGen = appendScoped(rules.codeGen, scopedGlob{relDir, "code_generated_paths in " + fragmentSource, newGlobMatcher(fragment.CodeGeneratedPaths, t.foldCase)})
+		}
+		if verbose {
+			fmt.Printf("Applying overrides from %s\n", fragmentSource)
+		}
+	}
+
+	return rules
+}
+
+// appendScoped returns a copy of globs with one more scope, leaving the
+// parent's slice untouched
+func appendScoped(globs []scopedGlob, glob scopedGlob) []scopedGlob {
+	scoped := make([]scopedGlob, len(globs), len(globs)+1)
+	copy(scoped, globs)
+	return append(scoped, glob)
+}
+
+// readIgnoreFile reads gitignore-style patterns, skipping blank lines and
+// # comments
+func readIgnoreFile(ignorePath string) ([]string, error) {
+	f, err := os.Open(ignorePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+// matchScoped evaluates scopes from the root down; the last scope with a
+// matching pattern decides. It also describes the deciding pattern and its
+// source, or returns "" when no pattern matched.
+func matchScoped(scopes []scopedGlob, relPath string, isDir bool) (bool, string) {
+	relPath = filepath.ToSlash(relPath)
+	matched, rule := false, ""
+	for _, s := range scopes {
+		scopedPath := relPath
+		if s.dir != "" {
+			scopedPath = strings.TrimPrefix(relPath, s.dir+"/")
+		}
+		if i, m := s.matcher.Rule(scopedPath, isDir); i >= 0 {
+			matched, rule = m, s.matcher.Pattern(i)+" in "+s.source
+		}
+	}
+	return matched, rule
+}
+
+// isIgnored reports whether relPath is ignored and by which rule
+func (r *ruleSet) isIgnored(relPath string, isDir bool) (bool, string) {
+	return matchScoped(r.ignore, relPath, isDir)
+}
+
+// isCodeGenerated reports whether relPath is code-generated and by which rule
+func (r *ruleSet) isCodeGenerated(relPath string) (bool, string) {
+	return matchScoped(r.codeGen, relPath, false)
+}
+
+func (r *ruleSet) hasType(ext string) bool {
+	return hasFileType(ext, r.types)
+}
@@ -0,0 +1,349 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Reasons a candidate file is left out of a rollup
+const (
+	reasonIgnored   = "ignored"
+	reasonExtension = "wrong extension"
+	reasonHidden    = "hidden"
+	reasonHiddenDir = "hidden directory"
+	reasonBinary    = "binary"
+	reasonTooLarge  = "too large"
+	reasonSymlink   = "symlink"
+	reasonDirectory = "directory"
+	reasonMissing   = "unreadable"
+	reasonOutside   = "outside project"
+)
+
+// binarySniffSize is how much of a file is checked for NUL bytes
+const binarySniffSize = 8000
+
+// fileEntry is a candidate file and the decision made about it
+type fileEntry struct {
+	// path is the absolute path of the file
+	path string
+
+	// relPath is the path relative to the project root
+	relPath string
+
+	info os.FileInfo
+
+	// reason explains why the file is excluded; empty when it is included
+	reason string
+
+	// rule describes the setting that made the decision
+	rule string
+
+	// codeGen marks files matching a code-generated pattern
+	codeGen bool
+
+	// codeGenRule describes the pattern that marked the file code-generated
+	codeGenRule string
+
+	// linkTarget is the target of a symlink listed with symlinks: list
+	linkTarget string
+
+	// role is what the file is for: source, test, docs and so on
+	role string
+
+	// module is the path of the Go module holding the file, if any
+	module string
+}
+
+func (e *fileEntry) included() bool {
+	return e.reason == ""
+}
+
+// selector decides which files of a project go into a rollup
+type selector struct {
+	opts    *rollupOptions
+	tree    *ruleTree
+	include *globMatcher
+	modules *moduleResolver
+
+	// skipPath is never selected; it is the rollup being written
+	skipPath string
+}
+
+func newSelector(opts *rollupOptions, skipPath string) *selector {
+	return &selector{
+		opts:     opts,
+		tree:     newRuleTree(opts),
+		include:  newGlobMatcher(opts.include, opts.foldCase),
+		modules:  newModuleResolver(),
+		skipPath: skipPath,
+	}
+}
+
+// selectFiles returns every candidate file with its decision, either from
+// the explicit file list, in the order given, or from walking the project
+// in path order
+func (s *selector) selectFiles() ([]*fileEntry, error) {
+	if s.opts.fileList != nil {
+		return s.selectListed(), nil
+	}
+
+	var entries []*fileEntry
+	err := walkTree(s.opts.root, s.opts.symlinks, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == s.skipPath {
+			return nil
+		}
+		relPath, _ := filepath.Rel(s.opts.root, path)
+		if info.IsDir() {
+			if path != s.opts.root && isHiddenPath(relPath) &&
+				!allowHidden(relPath, true, s.opts.includeHidden, s.include) && !s.include.MayMatchBelow(relPath) {
+				if verbose {
+					fmt.Printf("Skipping hidden directory: %s\n", relPath)
+				}
+				entries = append(entries, &fileEntry{path: path, relPath: relPath, info: info, reason: reasonHiddenDir, rule: s.hiddenRule()})
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isSymlink(info) && s.opts.symlinks != symlinksList {
+			// Links reach here unresolved when skipped or, when followed,
+			// when broken
+			entry := &fileEntry{path: path, relPath: relPath, info: info, reason: reasonSymlink, rule: "symlinks: skip"}
+			if s.opts.symlinks == symlinksFollow {
+				entry.reason, entry.rule = reasonMissing, "broken symlink"
+			}
+			if verbose {
+				fmt.Printf("Skipping %s: %s\n", entry.rule, relPath)
+			}
+			entries = append(entries, entry)
+			return nil
+		}
+		if isHiddenPath(relPath) && !allowHidden(relPath, false, s.opts.includeHidden, s.include) {
+			if verbose {
+				fmt.Printf("Skipping hidden file: %s\n", relPath)
+			}
+			entries = append(entries, &fileEntry{path: path, relPath: relPath, info: info, reason: reasonHidden, rule: s.hiddenRule()})
+			return nil
+		}
+		entries = append(entries, s.decide(path, relPath, info, true))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error walking through directory: %v", err)
+	}
+	return entries, nil
+}
+
+// selectListed resolves the explicit file list against the project root
+func (s *selector) selectListed() []*fileEntry {
+	var entries []*fileEntry
+	for _, listed := range s.opts.fileList {
+		filePath := listed
+		if !filepath.IsAbs(filePath) {
+			filePath = filepath.Join(s.opts.root, filePath)
+		}
+		relPath, err := filepath.Rel(s.opts.root, filePath)
+		if err != nil {
+			relPath = listed
+		}
+		entry := &fileEntry{path: filePath, relPath: relPath}
+		if !withinRoot(s.opts.root, filePath) {
+			fmt.Printf("Skipping %s: it is outside the project directory %s\n", listed, s.opts.root)
+			entry.reason, entry.rule = reasonOutside, "not below "+s.opts.root
+			entries = append(entries, entry)
+			continue
+		}
+
+		info, err := os.Lstat(filePath)
+		if err == nil && isSymlink(info) {
+			if s.opts.symlinks == symlinksSkip {
+				if verbose {
+					fmt.Printf("Skipping symlink: %s\n", listed)
+				}
+				entry.info, entry.reason, entry.rule = info, reasonSymlink, "symlinks: skip"
+				entries = append(entries, entry)
+				continue
+			}
+			if s.opts.symlinks == symlinksFollow {
+				info, err = os.Stat(filePath)
+			}
+		}
+		if err != nil {
+			fmt.Printf("Error reading file %s: %v\n", listed, err)
+			entry.reason, entry.rule = reasonMissing, err.Error()
+			entries = append(entries, entry)
+			continue
+		}
+		if info.IsDir() {
+			if verbose {
+				fmt.Printf("Skipping directory: %s\n", listed)
+			}
+			entry.info, entry.reason, entry.rule = info, reasonDirectory, "only files can be listed"
+			entries = append(entries, entry)
+			continue
+		}
+		entries = append(entries, s.decide(filePath, relPath, info, s.opts.filterList))
+	}
+	return entries
+}
+
+// decide applies the ignore, type, size and code-generated rules to one file
+func (s *selector) decide(path, relPath string, info os.FileInfo, filtered bool) *fileEntry {
+	entry := &fileEntry{path: path, relPath: relPath, info: info, role: fileRole(relPath)}
+	rules := s.tree.rulesFor(filepath.Dir(relPath))
+
+	if mod := s.modules.moduleFor(filepath.Dir(path)); mod != nil {
+		entry.module = mod.path
+	}
+	if filtered && len(s.opts.modules) > 0 && !s.inModules(entry.module) {
+		entry.reason = reasonOtherModule
+		entry.rule = fmt.Sprintf("module %q is not in %s (%s)", entry.module, s.opts.modulesSource, strings.Join(s.opts.modules, ", "))
+		if entry.module == "" {
+			entry.rule = fmt.Sprintf("not in a Go module; %s selects %s", s.opts.modulesSource, strings.Join(s.opts.modules, ", "))
+		}
+		return entry
+	}
+
+	var focusRule string
+	if filtered && s.opts.focus != nil {
+		var inFocus bool
+		inFocus, focusRule = s.opts.focus.decide(path)
+		if !inFocus {
+			entry.reason, entry.rule = reasonOutsideFocus, focusRule
+			return entry
+		}
+	}
+
+	// Check if the file should be ignored
+	ignored, rule := rules.isIgnored(relPath, false)
+	if filtered && ignored {
+		if verbose {
+			fmt.Printf("Ignoring file: %s\n", relPath)
+		}
+		entry.reason, entry.rule = reasonIgnored, rule
+		return entry
+	}
+
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	dirLink := isSymlink(info) && isDirLink(path)
+	if filtered && !rules.hasType(ext) && !dirLink {
+		entry.reason = reasonExtension
+		entry.rule = fmt.Sprintf("%q is not in %s (%s)", ext, rules.typesSource, strings.Join(rules.types, ", "))
+		return entry
+	}
+	switch {
+	case !filtered:
+		entry.rule = "listed with --no-filter"
+	case dirLink:
+		entry.rule = "symlinked directory listed with symlinks: list"
+	default:
+		entry.rule = fmt.Sprintf("%q is in %s", ext, rules.typesSource)
+		if rule != "" {
+			entry.rule += "; re-included by " + rule
+		}
+	}
+	if focusRule != "" {
+		entry.rule += "; " + focusRule
+	}
+
+	if filtered && len(s.opts.roles) > 0 && !slices.Contains(s.opts.roles, entry.role) {
+		entry.reason = reasonRole
+		entry.rule = fmt.Sprintf("%s is not in %s (%s)", entry.role, s.opts.rolesSource, strings.Join(s.opts.roles, ", "))
+		return entry
+	}
+	if filtered && entry.role == roleTest && s.opts.tests == testsOmit {
+		entry.reason, entry.rule = reasonTestOmitted, "tests: omit"
+		return entry
+	}
+
+	// Check if the file is code-generated
+	entry.codeGen, entry.codeGenRule = rules.isCodeGenerated(relPath)
+
+	if isSymlink(info) {
+		target, err := os.Readlink(path)
+		if err != nil {
+			fmt.Printf("Error reading symlink %s: %v\n", path, err)
+			entry.reason, entry.rule = reasonMissing, err.Error()
+			return entry
+		}
+		entry.linkTarget = filepath.ToSlash(target)
+		return entry
+	}
+
+	if s.opts.maxFileSize > 0 && info.Size() > s.opts.maxFileSize {
+		entry.reason = reasonTooLarge
+		entry.rule = fmt.Sprintf("%s exceeds %s (%s)", humanReadableSize(info.Size()), s.opts.maxSizeSource, humanReadableSize(s.opts.maxFileSize))
+		return entry
+	}
+
+	binary, err := isBinaryFile(path)
+	if err != nil {
+		entry.reason, entry.rule = reasonMissing, err.Error()
+		return entry
+	}
+	if binary {
+		entry.reason, entry.rule = reasonBinary, "contains NUL bytes"
+	}
+	return entry
+}
+
+// inModules reports whether modPath matches one of the selected modules
+func (s *selector) inModules(modPath string) bool {
+	for _, pattern := range s.opts.modules {
+		if modPath != "" && matchModule(pattern, modPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// hiddenRule describes why hidden paths are skipped
+func (s *selector) hiddenRule() string {
+	if s.opts.includeHidden {
+		return ".git is only included through include_paths"
+	}
+	return "include_hidden is off and no include_paths pattern matches"
+}
+
+// isBinaryFile reports whether the start of the file contains a NUL byte,
+// the same heuristic git uses to tell binary files from text
+func isBinaryFile(filePath string) (bool, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, binarySniffSize)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	return bytes.IndexByte(buf[:n], 0) >= 0, nil
+}
+
+// describe returns a one-line summary of the decision for dry runs
+func (e *fileEntry) describe() string {
+	decision := "included"
+	if !e.included() {
+		decision = e.reason
+	}
+	detail := e.rule
+	if e.included() && e.codeGen {
+		detail += "; code-generated by " + e.codeGenRule
+	}
+	if e.linkTarget != "" {
+		detail += "; symlink to " + e.linkTarget
+	}
+	if detail == "" {
+		return fmt.Sprintf("%-16s %s", decision, e.relPath)
+	}
+	return fmt.Sprintf("%-16s %s  (%s)", decision, e.relPath, detail)
+}
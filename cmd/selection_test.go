@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelectorDecisions(t *testing.T) {
+	root := t.TempDir()
+
+	files := map[string]string{
+		"main.go":                  "package main\n",
+		"docs/guide.md":            "# Guide\n",
+		"docs/notes.md":            "# Notes\n",
+		"vendor/lib.go":            "package lib\n",
+		"gen/model.go":             "package gen\n",
+		"data.json":                "{}\n",
+		"image.txt":                "GIF89a\x00\x01",
+		"large.txt":                strings.Repeat("x", 2048),
+		".github/workflows/ci.yml": "on: push\n",
+	}
+	writeTree(t, root, files)
+
+	opts := &rollupOptions{
+		root:          root,
+		types:         []string{"go", "md", "txt"},
+		ignore:        []string{"vendor/**", "*.md", "!guide.md"},
+		codeGen:       []string{"gen/**"},
+		symlinks:      symlinksFollow,
+		maxFileSize:   1024,
+		filterList:    true,
+		typesSource:   "file_extensions",
+		ignoreSource:  "ignore_paths",
+		codeGenSource: "code_generated_paths",
+		maxSizeSource: "max_file_size",
+	}
+
+	entries, err := newSelector(opts, "").selectFiles()
+	if err != nil {
+		t.Fatalf("selectFiles() failed: %v", err)
+	}
+
+	expected := map[string]struct {
+		reason string
+		rule   string
+	}{
+		".github":       {reasonHiddenDir, "include_hidden is off"},
+		"data.json":     {reasonExtension, `"json" is not in file_extensions`},
+		"docs/guide.md": {"", "re-included by !guide.md in ignore_paths"},
+		"docs/notes.md": {reasonIgnored, "*.md in ignore_paths"},
+		"gen/model.go":  {"", `"go" is in file_extensions`},
+		"image.txt":     {reasonBinary, "NUL"},
+		"large.txt":     {reasonTooLarge, "exceeds max_file_size"},
+		"main.go":       {"", `"go" is in file_extensions`},
+		"vendor/lib.go": {reasonIgnored, "vendor/** in ignore_paths"},
+	}
+	if len(entries) != len(expected) {
+		for _, e := range entries {
+			t.Log(e.describe())
+		}
+		t.Fatalf("selectFiles() returned %d entries; want %d", len(entries), len(expected))
+	}
+	for _, entry := range entries {
+		want, ok := expected[filepath.ToSlash(entry.relPath)]
+		if !ok {
+			t.Errorf("Unexpected entry %s", entry.relPath)
+			continue
+		}
+		if entry.reason != want.reason {
+			t.Errorf("%s: reason = %q; want %q", entry.relPath, entry.reason, want.reason)
+		}
+		if !strings.Contains(entry.rule, want.rule) {
+			t.Errorf("%s: rule = %q; want it to contain %q", entry.relPath, entry.rule, want.rule)
+		}
+		if got := filepath.ToSlash(entry.relPath) == "gen/model.go"; entry.codeGen != got {
+			t.Errorf("%s: codeGen = %v; want %v", entry.relPath, entry.codeGen, got)
+		}
+	}
+
+	s := newSelector(opts, "")
+	entry, err := s.explain(filepath.Join(root, ".github", "workflows", "ci.yml"))
+	if err != nil {
+		t.Fatalf("explain() failed: %v", err)
+	}
+	if entry.reason != reasonHidden {
+		t.Errorf("explain(ci.yml) reason = %q; want %q", entry.reason, reasonHidden)
+	}
+	entry, err = s.explain("gen/model.go")
+	if err != nil {
+		t.Fatalf("explain() failed: %v", err)
+	}
+	if !entry.included() || entry.codeGenRule != "gen/** in code_generated_paths" {
+		t.Errorf("explain(gen/model.go) = %+v", entry)
+	}
+	if _, err := s.explain(filepath.Join(root, "..", "elsewhere.go")); err == nil {
+		t.Errorf("explain() expected an error for a path outside the project")
+	}
+}
+
+func TestSelectorRoles(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"main.go": "x\n", "main_test.go": "x\n", "README.md": "x\n", "testdata/input.go": "x\n"})
+
+	tests := []struct {
+		name  string
+		roles []string
+		tests string
+		want  map[string]string
+	}{
+		{
+			name:  "source and tests",
+			roles: []string{roleSource, roleTest},
+			want:  map[string]string{"main.go": "", "main_test.go": "", "README.md": reasonRole, "testdata/input.go": reasonRole},
+		},
+		{
+			name:  "tests omitted",
+			tests: testsOmit,
+			want:  map[string]string{"main.go": "", "main_test.go": reasonTestOmitted, "README.md": "", "testdata/input.go": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &rollupOptions{
+				root:        root,
+				types:       []string{"go", "md"},
+				symlinks:    symlinksFollow,
+				filterList:  true,
+				roles:       tt.roles,
+				rolesSource: "roles",
+				tests:       tt.tests,
+			}
+			entries, err := newSelector(opts, "").selectFiles()
+			if err != nil {
+				t.Fatalf("selectFiles() failed: %v", err)
+			}
+			got := make(map[string]string)
+			for _, entry := range entries {
+				got[filepath.ToSlash(entry.relPath)] = entry.reason
+			}
+			for path, reason := range tt.want {
+				if got[path] != reason {
+					t.Errorf("%s: reason = %q; want %q", path, got[path], reason)
+				}
+			}
+		})
+	}
+}
+
+func TestSelectorIncludeHidden(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"main.go":          "package main\n",
+		".env":             "A=1\n",
+		".git/HEAD":        "ref: refs/heads/main\n",
+		".git/.env":        "B=2\n",
+		".github/ci.yml":   "on: push\n",
+		".github/app/.env": "C=3\n",
+	})
+
+	opts := &rollupOptions{
+		root:       root,
+		types:      []string{"go", "env"},
+		include:    []string{".env"},
+		symlinks:   symlinksFollow,
+		filterList: true,
+	}
+	entries, err := newSelector(opts, "").selectFiles()
+	if err != nil {
+		t.Fatalf("selectFiles() failed: %v", err)
+	}
+
+	// Hidden directories are walked for the files the pattern matches, but
+	// .git is never entered for a pattern that does not name it
+	got := make(map[string]string)
+	for _, entry := range entries {
+		got[filepath.ToSlash(entry.relPath)] = entry.reason
+	}
+	want := map[string]string{
+		"main.go":          "",
+		".env":             "",
+		".git":             reasonHiddenDir,
+		".github/ci.yml":   reasonHidden,
+		".github/app/.env": "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("selectFiles() = %v; want %v", got, want)
+	}
+}
+
+func TestSelectorListedOutsideRoot(t *testing.T) {
+	tempDir := t.TempDir()
+	root := filepath.Join(tempDir, "project")
+	writeTree(t, tempDir, map[string]string{
+		"outside.go":       "package outside\n",
+		".rollupignore":    "main.go\n",
+		"project/main.go":  "package main\n",
+		"project/sub/a.go": "package sub\n",
+	})
+
+	opts := &rollupOptions{
+		root:       root,
+		types:      []string{"go"},
+		symlinks:   symlinksSkip,
+		filterList: true,
+		fileList:   []string{"../outside.go", "main.go", "sub/../../outside.go", filepath.Join(tempDir, "outside.go"), "sub/a.go"},
+	}
+	entries, err := newSelector(opts, "").selectFiles()
+	if err != nil {
+		t.Fatalf("selectFiles() failed: %v", err)
+	}
+
+	// Paths outside the root are skipped, and the rules above it are not read
+	want := []string{reasonOutside, "", reasonOutside, reasonOutside, ""}
+	if len(entries) != len(want) {
+		t.Fatalf("selectFiles() returned %d entries; want %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		if entry.reason != want[i] {
+			t.Errorf("%s: reason = %q; want %q", opts.fileList[i], entry.reason, want[i])
+		}
+	}
+}
+
+func TestSelectorSymlinks(t *testing.T) {
+	tempDir := t.TempDir()
+	root := filepath.Join(tempDir, "project")
+	writeTree(t, tempDir, map[string]string{
+		"common/shared.go": "package common\n",
+		"project/main.go":  "package main\n",
+	})
+	for link, target := range map[string]string{"alias.go": "main.go", "shared": "../common", "broken.go": "missing.go"} {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Skipf("Symlinks are not supported: %v", err)
+		}
+	}
+
+	tests := []struct {
+		policy string
+		want   map[string]string
+	}{
+		{symlinksSkip, map[string]string{"main.go": "", "alias.go": reasonSymlink, "shared": reasonSymlink, "broken.go": reasonSymlink}},
+		{symlinksFollow, map[string]string{"main.go": "", "alias.go": "", "shared/shared.go": "", "broken.go": reasonMissing}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			opts := &rollupOptions{root: root, types: []string{"go"}, symlinks: tt.policy, filterList: true}
+			s := newSelector(opts, "")
+			entries, err := s.selectFiles()
+			if err != nil {
+				t.Fatalf("selectFiles() failed: %v", err)
+			}
+
+			// Every link is listed with its decision, and explain agrees
+			// with the walk about what is rolled up
+			got := make(map[string]string)
+			included := make(map[string]bool)
+			for _, entry := range entries {
+				got[filepath.ToSlash(entry.relPath)] = entry.reason
+				included[filepath.ToSlash(entry.relPath)] = entry.included()
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectFiles() = %v; want %v", got, tt.want)
+			}
+			for _, name := range []string{"main.go", "alias.go", "shared/shared.go"} {
+				entry, err := s.explain(filepath.Join(root, name))
+				if err != nil {
+					t.Fatalf("explain(%s) failed: %v", name, err)
+				}
+				if entry.included() != included[name] {
+					t.Errorf("explain(%s) included = %v; the walk included = %v (%s)", name, entry.included(), included[name], entry.rule)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// Output types for file rollups, matching the web command
+const (
+	outputSingle   = "single"
+	outputSeparate = "separate"
+)
+
+// sectionGroup is the set of files written to one rollup with
+// output_type: separate
+type sectionGroup struct {
+	// Key is the directory the group stands for, "." for the project root
+	Key string
+
+	// FileName is the name of the group's rollup file
+	FileName string
+
+	Files []*fileSection
+}
+
+// splitKey returns the directory a file is grouped under: the first depth
+// elements of its directory, or its whole directory (its Go package) when
+// depth is 0
+func splitKey(filePath string, depth int) string {
+	dir := filepath.ToSlash(filepath.Dir(filepath.FromSlash(filePath)))
+	if dir == "." || depth <= 0 {
+		return dir
+	}
+	parts := strings.Split(dir, "/")
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return strings.Join(parts, "/")
+}
+
+var groupNameReplacer = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// groupSections splits files into groups in the order their first file
+// appears
+func groupSections(files []*fileSection, depth int) []*sectionGroup {
+	var groups []*sectionGroup
+	byKey := make(map[string]*sectionGroup)
+	usedNames := make(map[string]bool)
+	for _, f := range files {
+		key := splitKey(f.Path, depth)
+		g := byKey[key]
+		if g == nil {
+			name := "root"
+			if key != "." {
+				name = strings.Trim(groupNameReplacer.ReplaceAllString(key, "-"), "-")
+			}
+			// Keep names unique when different directories sanitize alike
+			for base, n := name, 2; usedNames[name]; n++ {
+				name = fmt.Sprintf("%s-%d", base, n)
+			}
+			usedNames[name] = true
+			g = &sectionGroup{Key: key, FileName: name + ".rollup.md"}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		g.Files = append(g.Files, f)
+	}
+	return groups
+}
+
+// writeIndex writes a Markdown index linking every group's rollup
+func writeIndex(w io.Writer, project string, groups []*sectionGroup) {
+	fmt.Fprintf(w, "# %s\n\n", project)
+	fmt.Fprintf(w, "| Rollup | Directory | Files | Size | Tokens |\n")
+	fmt.Fprintf(w, "|--------|-----------|-------|------|--------|\n")
+	for _, g := range groups {
+		var size int64
+		tokens := 0
+		for _, f := range g.Files {
+			size += f.Size
+			tokens += f.Tokens
+		}
+		fmt.Fprintf(w, "| [%s](%s) | `%s` | %d | %s | ~%d |\n",
+			strings.TrimSuffix(g.FileName, ".rollup.md"), g.FileName, g.Key, len(g.Files), humanReadableSize(size), tokens)
+	}
+}
+
+// writeSeparate writes one rollup per group into outputDir, plus an
+// index.md linking them, and returns the number of rollups written
+func writeSeparate(outputDir string, tmpl *template.Template, data *templateData, opts *rollupOptions) (int, error) {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	groups := groupSections(data.Files, opts.splitDepth)
+	for _, g := range groups {
+		stats := newRollupStats()
+		for _, f := range g.Files {
+			stats.addIncluded(f.entry, f.text())
+		}
+		stats.finish()
+
+		groupData := *data
+		groupData.Files = g.Files
+		groupData.Stats = stats
+
+		groupFile, err := os.Create(filepath.Join(outputDir, g.FileName))
+		if err != nil {
+			return 0, fmt.Errorf("error creating output file: %v", err)
+		}
+		err = renderRollup(groupFile, tmpl, &groupData, opts)
+		groupFile.Close()
+		if err != nil {
+			return 0, err
+		}
+		if verbose {
+			fmt.Printf("Wrote %s (%d files)\n", g.FileName, len(g.Files))
+		}
+	}
+
+	indexFile, err := os.Create(filepath.Join(outputDir, "index.md"))
+	if err != nil {
+		return 0, fmt.Errorf("error creating index file: %v", err)
+	}
+	defer indexFile.Close()
+	writeIndex(indexFile, data.Project, groups)
+	return len(groups), nil
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tnypxl/rollup/internal/config"
+)
+
+func TestSplitKey(t *testing.T) {
+	tests := []struct {
+		path  string
+		depth int
+		want  string
+	}{
+		{"main.go", 1, "."},
+		{"main.go", 0, "."},
+		{"cmd/files.go", 1, "cmd"},
+		{"internal/config/config.go", 1, "internal"},
+		{"internal/config/config.go", 2, "internal/config"},
+		{"internal/config/config.go", 5, "internal/config"},
+		{"internal/config/config.go", 0, "internal/config"},
+	}
+
+	for _, tt := range tests {
+		if got := splitKey(tt.path, tt.depth); got != tt.want {
+			t.Errorf("splitKey(%q, %d) = %q, want %q", tt.path, tt.depth, got, tt.want)
+		}
+	}
+}
+
+func TestGroupSections(t *testing.T) {
+	files := []*fileSection{
+		{Path: "main.go"},
+		{Path: "cmd/files.go"},
+		{Path: "internal/config/config.go"},
+		{Path: "cmd/web.go"},
+		{Path: "internal/scraper/scraper.go"},
+		{Path: "internal-config/x.go"},
+	}
+
+	tests := []struct {
+		name  string
+		depth int
+		want  map[string][]string
+		order []string
+	}{
+		{
+			name:  "top level",
+			depth: 1,
+			order: []string{"root.rollup.md", "cmd.rollup.md", "internal.rollup.md", "internal-config.rollup.md"},
+			want: map[string][]string{
+				"root.rollup.md":            {"main.go"},
+				"cmd.rollup.md":             {"cmd/files.go", "cmd/web.go"},
+				"internal.rollup.md":        {"internal/config/config.go", "internal/scraper/scraper.go"},
+				"internal-config.rollup.md": {"internal-config/x.go"},
+			},
+		},
+		{
+			name:  "per package",
+			depth: 0,
+			order: []string{"root.rollup.md", "cmd.rollup.md", "internal-config.rollup.md", "internal-scraper.rollup.md", "internal-config-2.rollup.md"},
+			want: map[string][]string{
+				"root.rollup.md":              {"main.go"},
+				"cmd.rollup.md":               {"cmd/files.go", "cmd/web.go"},
+				"internal-config.rollup.md":   {"internal/config/config.go"},
+				"internal-scraper.rollup.md":  {"internal/scraper/scraper.go"},
+				"internal-config-2.rollup.md": {"internal-config/x.go"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups := groupSections(files, tt.depth)
+			var order []string
+			got := make(map[string][]string)
+			for _, g := range groups {
+				order = append(order, g.FileName)
+				for _, f := range g.Files {
+					got[g.FileName] = append(got[g.FileName], f.Path)
+				}
+			}
+			if !reflect.DeepEqual(order, tt.order) {
+				t.Errorf("group order = %v, want %v", order, tt.order)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groups = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunRollupSeparate(t *testing.T) {
+	tempDir := t.TempDir()
+
+	files := map[string]string{
+		"main.go":                   "package main\n",
+		"cmd/files.go":              "package cmd\n",
+		"internal/config/config.go": "package config\n",
+	}
+	writeTree(t, tempDir, files)
+
+	originalWd, _ := os.Getwd()
+	os.Chdir(tempDir)
+	defer os.Chdir(originalWd)
+
+	cfg = &config.Config{FileExtensions: []string{"go"}, OutputType: outputSeparate}
+	if err := runRollup(cfg); err != nil {
+		t.Fatalf("runRollup() returned error: %v", err)
+	}
+
+	dirs, _ := filepath.Glob("*.rollup")
+	if len(dirs) != 1 {
+		t.Fatalf("Expected 1 output directory, got %d", len(dirs))
+	}
+
+	want := map[string]string{
+		"root.rollup.md":     "# File: main.go",
+		"cmd.rollup.md":      "# File: cmd/files.go",
+		"internal.rollup.md": "# File: internal/config/config.go",
+	}
+	for name, section := range want {
+		content, err := os.ReadFile(filepath.Join(dirs[0], name))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		if !strings.Contains(string(content), section) {
+			t.Errorf("%s does not contain %q", name, section)
+		}
+		if strings.Count(string(content), "# File:") != 1 {
+			t.Errorf("%s contains files from other groups:\n%s", name, content)
+		}
+	}
+
+	index, err := os.ReadFile(filepath.Join(dirs[0], "index.md"))
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	for name := range want {
+		if !strings.Contains(string(index), "("+name+")") {
+			t.Errorf("index does not link %s:\n%s", name, index)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// statsTopFiles is the number of largest files listed in the run summary
+const statsTopFiles = 10
+
+// rollupStats summarizes a file rollup run
+type rollupStats struct {
+	FilesScanned       int                   `json:"files_scanned"`
+	FilesIncluded      int                   `json:"files_included"`
+	FilesIgnored       int                   `json:"files_ignored"`
+	FilesCodeGenerated int                   `json:"files_code_generated"`
+	FilesExcluded      map[string]int        `json:"files_excluded"`
+	Bytes              int64                 `json:"bytes"`
+	Lines              int                   `json:"lines"`
+	Tokens             int                   `json:"estimated_tokens"`
+	Largest            []fileStats           `json:"largest_files"`
+	ByExtension        map[string]*fileStats `json:"by_extension"`
+	ByDirectory        map[string]*fileStats `json:"by_directory"`
+
+	files []fileStats
+}
+
+// fileStats holds the totals for a single file or a group of files
+type fileStats struct {
+	Path   string `json:"path,omitempty"`
+	Files  int    `json:"files,omitempty"`
+	Bytes  int64  `json:"bytes"`
+	Lines  int    `json:"lines"`
+	Tokens int    `json:"estimated_tokens"`
+}
+
+func newRollupStats() *rollupStats {
+	return &rollupStats{
+		ByExtension:   make(map[string]*fileStats),
+		ByDirectory:   make(map[string]*fileStats),
+		FilesExcluded: make(map[string]int),
+	}
+}
+
+// addSkipped counts a candidate file that was left out of the rollup for
+// reason. Only files left out by ignore patterns count as ignored.
+func (s *rollupStats) addSkipped(reason string) {
+	s.FilesScanned++
+	s.FilesExcluded[reason]++
+	if reason == reasonIgnored {
+		s.FilesIgnored++
+	}
+}
+
+// addIncluded counts a file written to the rollup with its content
+func (s *rollupStats) addIncluded(entry *fileEntry, content string) {
+	s.FilesScanned++
+	s.FilesIncluded++
+	if entry.codeGen {
+		s.FilesCodeGenerated++
+	}
+
+	f := fileStats{
+		Path:   filepath.ToSlash(entry.relPath),
+		Bytes:  int64(len(content)),
+		Lines:  countLines(content),
+		Tokens: estimateTokens(content),
+	}
+	s.Bytes += f.Bytes
+	s.Lines += f.Lines
+	s.Tokens += f.Tokens
+	s.files = append(s.files, f)
+
+	ext := strings.TrimPrefix(filepath.Ext(entry.relPath), ".")
+	if ext == "" {
+		ext = "(none)"
+	}
+	dir, _, found := strings.Cut(f.Path, "/")
+	if !found {
+		dir = "."
+	}
+	for _, group := range []*fileStats{groupStats(s.ByExtension, ext), groupStats(s.ByDirectory, dir)} {
+		group.Files++
+		group.Bytes += f.Bytes
+		group.Lines += f.Lines
+		group.Tokens += f.Tokens
+	}
+}
+
+func groupStats(groups map[string]*fileStats, key string) *fileStats {
+	if groups[key] == nil {
+		groups[key] = &fileStats{}
+	}
+	return groups[key]
+}
+
+// finish ranks the largest files once every file has been counted
+func (s *rollupStats) finish() {
+	largest := append([]fileStats(nil), s.files...)
+	sort.SliceStable(largest, func(i, j int) bool { return largest[i].Bytes > largest[j].Bytes })
+	if len(largest) > statsTopFiles {
+		largest = largest[:statsTopFiles]
+	}
+	s.Largest = largest
+}
+
+// print writes a human-readable summary of the run
+func (s *rollupStats) print(w io.Writer) {
+	fmt.Fprintf(w, "\nFiles: %d scanned, %d included, %d ignored, %d code-generated\n",
+		s.FilesScanned, s.FilesIncluded, s.FilesIgnored, s.FilesCodeGenerated)
+	if len(s.FilesExcluded) > 0 {
+		reasons := make([]string, 0, len(s.FilesExcluded))
+		for reason := range s.FilesExcluded {
+			reasons = append(reasons, reason)
+		}
+		sort.Strings(reasons)
+		for i, reason := range reasons {
+			reasons[i] = fmt.Sprintf("%d %s", s.FilesExcluded[reason], reason)
+		}
+		fmt.Fprintf(w, "Excluded: %s\n", strings.Join(reasons, ", "))
+	}
+	fmt.Fprintf(w, "Size: %s, %d lines, ~%d tokens\n", humanReadableSize(s.Bytes), s.Lines, s.Tokens)
+
+	if len(s.Largest) > 0 {
+		fmt.Fprintln(w, "\nLargest files:")
+		for _, f := range s.Largest {
+			fmt.Fprintf(w, "  %10s  %s\n", humanReadableSize(f.Bytes), f.Path)
+		}
+	}
+	printGroups(w, "By extension:", s.ByExtension)
+	printGroups(w, "By directory:", s.ByDirectory)
+}
+
+// printGroups lists groups from largest to smallest
+func printGroups(w io.Writer, title string, groups map[string]*fileStats) {
+	if len(groups) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if groups[keys[i]].Bytes != groups[keys[j]].Bytes {
+			return groups[keys[i]].Bytes > groups[keys[j]].Bytes
+		}
+		return keys[i] < keys[j]
+	})
+
+	fmt.Fprintf(w, "\n%s\n", title)
+	for _, key := range keys {
+		g := groups[key]
+		fmt.Fprintf(w, "  %-20s %5d files  %10s  ~%d tokens\n", key, g.Files, humanReadableSize(g.Bytes), g.Tokens)
+	}
+}
+
+// writeJSON saves the summary to statsPath as JSON
+func (s *rollupStats) writeJSON(statsPath string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding stats: %v", err)
+	}
+	if err := os.WriteFile(statsPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing stats file: %v", err)
+	}
+	return nil
+}
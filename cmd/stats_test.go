@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRollupStats(t *testing.T) {
+	stats := newRollupStats()
+	stats.addSkipped(reasonIgnored)
+	stats.addIncluded(&fileEntry{relPath: "main.go"}, "package main\n")
+	stats.addIncluded(&fileEntry{relPath: "cmd/root.go"}, "package cmd\n\nvar x = 1\n")
+	stats.addIncluded(&fileEntry{relPath: "cmd/gen.go", codeGen: true}, "package cmd\n")
+	stats.addIncluded(&fileEntry{relPath: "docs/guide.md"}, "# Guide\n")
+	stats.addIncluded(&fileEntry{relPath: "Makefile"}, "all:\n")
+	for i := 0; i < 10; i++ {
+		stats.addIncluded(&fileEntry{relPath: fmt.Sprintf("pkg/f%d.txt", i)}, strings.Repeat("x", i))
+	}
+	stats.finish()
+
+	if stats.FilesScanned != 16 || stats.FilesIncluded != 15 || stats.FilesIgnored != 1 || stats.FilesCodeGenerated != 1 {
+		t.Errorf("Unexpected file counts: %+v", stats)
+	}
+	if stats.Lines != 1+3+1+1+1+9 {
+		t.Errorf("Lines = %d; want %d", stats.Lines, 16)
+	}
+	if len(stats.Largest) != statsTopFiles {
+		t.Fatalf("Largest has %d files; want %d", len(stats.Largest), statsTopFiles)
+	}
+	if stats.Largest[0].Path != "cmd/root.go" {
+		t.Errorf("Largest file = %s; want cmd/root.go", stats.Largest[0].Path)
+	}
+	if g := stats.ByDirectory["cmd"]; g == nil || g.Files != 2 {
+		t.Errorf("ByDirectory[cmd] = %+v; want 2 files", g)
+	}
+	if g := stats.ByDirectory["."]; g == nil || g.Files != 2 {
+		t.Errorf("ByDirectory[.] = %+v; want 2 files", g)
+	}
+	if g := stats.ByExtension["(none)"]; g == nil || g.Files != 1 {
+		t.Errorf("ByExtension[(none)] = %+v; want 1 file", g)
+	}
+
+	var out bytes.Buffer
+	stats.print(&out)
+	for _, expected := range []string{"16 scanned, 15 included, 1 ignored, 1 code-generated", "Largest files:", "By extension:", "By directory:"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Summary does not contain %q:\n%s", expected, out.String())
+		}
+	}
+
+	statsPath := filepath.Join(t.TempDir(), "stats.json")
+	if err := stats.writeJSON(statsPath); err != nil {
+		t.Fatalf("writeJSON() failed: %v", err)
+	}
+	data, err := os.ReadFile(statsPath)
+	if err != nil {
+		t.Fatalf("Failed to read stats file: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Stats file is not valid JSON: %v", err)
+	}
+	if decoded["files_included"] != float64(15) {
+		t.Errorf("files_included = %v; want 15", decoded["files_included"])
+	}
+}
+
+func TestRollupStatsExclusions(t *testing.T) {
+	stats := newRollupStats()
+	for _, reason := range []string{reasonIgnored, reasonExtension, reasonExtension, reasonHidden, reasonBinary} {
+		stats.addSkipped(reason)
+	}
+
+	if stats.FilesScanned != 5 || stats.FilesIgnored != 1 {
+		t.Errorf("Unexpected file counts: %+v", stats)
+	}
+	want := map[string]int{reasonIgnored: 1, reasonExtension: 2, reasonHidden: 1, reasonBinary: 1}
+	if !reflect.DeepEqual(stats.FilesExcluded, want) {
+		t.Errorf("FilesExcluded = %v; want %v", stats.FilesExcluded, want)
+	}
+
+	var out bytes.Buffer
+	stats.print(&out)
+	if expected := "Excluded: 1 binary, 1 hidden, 1 ignored, 2 wrong extension\n"; !strings.Contains(out.String(), expected) {
+		t.Errorf("Summary does not contain %q:\n%s", expected, out.String())
+	}
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Kinds of symbols in the symbol index
+const (
+	symbolType   = "type"
+	symbolFunc   = "func"
+	symbolMethod = "method"
+)
+
+// symbol is one exported declaration listed in the symbol index
+type symbol struct {
+	// Name is the symbol as written in Go, qualified by its package, e.g.
+	// config.Load or (*config.Config).Validate
+	Name string
+
+	Kind string
+
+	// Path and Line locate the declaration
+	Path string
+	Line int
+
+	// Anchor is the Markdown anchor of the file's section header
+	Anchor string
+}
+
+// collectSymbols lists the exported types, functions and methods declared
+// in the Go files among files, sorted by name. Test files are skipped.
+func collectSymbols(files []*fileSection, lineNumbers bool) []symbol {
+	var symbols []symbol
+	fset := token.NewFileSet()
+	for _, f := range files {
+		if !strings.HasSuffix(f.Path, ".go") || strings.HasSuffix(f.Path, "_test.go") || f.SymlinkTarget != "" {
+			continue
+		}
+		file, err := parser.ParseFile(fset, f.Path, f.Source, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		pkg := file.Name.Name
+		anchor := headingAnchor(sectionHeading(f, lineNumbers))
+		add := func(name, kind string, pos token.Pos) {
+			symbols = append(symbols, symbol{Name: name, Kind: kind, Path: f.Path, Line: fset.Position(pos).Line, Anchor: anchor})
+		}
+
+		for _, decl := range file.Decls {
+			switch d := decl.(type) {
+			case *ast.GenDecl:
+				if d.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range d.Specs {
+					if ts := spec.(*ast.TypeSpec); ts.Name.IsExported() {
+						add(pkg+"."+ts.Name.Name, symbolType, ts.Name.Pos())
+					}
+				}
+			case *ast.FuncDecl:
+				if !d.Name.IsExported() {
+					continue
+				}
+				if d.Recv == nil {
+					add(pkg+"."+d.Name.Name, symbolFunc, d.Name.Pos())
+					continue
+				}
+				recv, pointer := receiverType(d.Recv.List[0].Type)
+				if !ast.IsExported(recv) {
+					continue
+				}
+				name := fmt.Sprintf("%s.%s.%s", pkg, recv, d.Name.Name)
+				if pointer {
+					name = fmt.Sprintf("(*%s.%s).%s", pkg, recv, d.Name.Name)
+				}
+				add(name, symbolMethod, d.Name.Pos())
+			}
+		}
+	}
+
+	sort.SliceStable(symbols, func(i, j int) bool {
+		return symbolSortKey(symbols[i].Name) < symbolSortKey(symbols[j].Name)
+	})
+	return symbols
+}
+
+// receiverType returns the name of a method's receiver type and whether
+// the receiver is a pointer
+func receiverType(expr ast.Expr) (string, bool) {
+	pointer := false
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr, pointer = star.X, true
+	}
+	// Drop type parameters of generic receivers
+	switch t := expr.(type) {
+	case *ast.IndexExpr:
+		expr = t.X
+	case *ast.IndexListExpr:
+		expr = t.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name, pointer
+	}
+	return "", pointer
+}
+
+// symbolSortKey sorts methods right after their receiver type
+func symbolSortKey(name string) string {
+	return strings.TrimPrefix(strings.Replace(name, ")", "", 1), "(*")
+}
+
+// sectionHeading returns the text of a file's header in the default
+// template, which its anchor is derived from
+func sectionHeading(f *fileSection, lineNumbers bool) string {
+	heading := "File: " + f.Path
+	if f.CodeGenerated {
+		heading += " (Code-generated, Read-only)"
+	}
+	if f.SymlinkTarget != "" {
+		heading += " (Symlink to " + f.SymlinkTarget + ")"
+	} else if f.Summary != "" {
+		heading += " (Summary)"
+	} else {
+		if f.Notebook {
+			heading += " (Notebook)"
+		}
+		if lineNumbers {
+			heading += fmt.Sprintf(" (%d lines)", f.Lines)
+		}
+	}
+	return heading
+}
+
+// headingAnchor derives the anchor Markdown renderers such as GitHub give
+// a heading: lower case, punctuation dropped and spaces turned into hyphens
+func headingAnchor(heading string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case r == ' ':
+			b.WriteByte('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// symbolIndex renders symbols as a Markdown table linking each symbol to
+// its file's section, or returns "" when there are none
+func symbolIndex(symbols []symbol) string {
+	if len(symbols) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("# Symbol index\n\n")
+	b.WriteString("| Symbol | Kind | Location |\n")
+	b.WriteString("|--------|------|----------|\n")
+	for _, s := range symbols {
+		fmt.Fprintf(&b, "| `%s` | %s | [%s:%d](#%s) |\n", s.Name, s.Kind, s.Path, s.Line, s.Anchor)
+	}
+	return b.String()
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCollectSymbols(t *testing.T) {
+	source := `package store
+
+type Store struct{}
+
+type (
+	Key   string
+	value int
+)
+
+type List[T any] struct{}
+
+func New() *Store { return nil }
+
+func helper() {}
+
+func (s *Store) Get(k Key) {}
+
+func (s *Store) lock() {}
+
+func (k Key) String() string { return "" }
+
+func (l List[T]) Len() int { return 0 }
+
+func (v value) Exported() {}
+`
+	files := []*fileSection{
+		{Path: "store/store.go", Source: source},
+		{Path: "store/store_test.go", Source: "package store\n\nfunc TestGet() {}\n"},
+		{Path: "broken.go", Source: "package"},
+		{Path: "README.md", Source: "# Store\n"},
+	}
+
+	var got []string
+	for _, s := range collectSymbols(files, false) {
+		got = append(got, s.Name+" "+s.Kind)
+	}
+	want := []string{
+		"store.Key type",
+		"store.Key.String method",
+		"store.List type",
+		"store.List.Len method",
+		"store.New func",
+		"store.Store type",
+		"(*store.Store).Get method",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("collectSymbols() = %v, want %v", got, want)
+	}
+
+	symbols := collectSymbols(files[:1], false)
+	if s := symbols[len(symbols)-1]; s.Line != 16 || s.Path != "store/store.go" || s.Anchor != "file-storestorego" {
+		t.Errorf("symbol location = %+v", s)
+	}
+}
+
+func TestHeadingAnchor(t *testing.T) {
+	tests := []struct {
+		section     *fileSection
+		lineNumbers bool
+		want        string
+	}{
+		{&fileSection{Path: "main.go"}, false, "file-maingo"},
+		{&fileSection{Path: "internal/config/config.go", Lines: 42}, true, "file-internalconfigconfiggo-42-lines"},
+		{&fileSection{Path: "gen/api_v2.go", CodeGenerated: true}, false, "file-genapi_v2go-code-generated-read-only"},
+	}
+
+	for _, tt := range tests {
+		if got := headingAnchor(sectionHeading(tt.section, tt.lineNumbers)); got != tt.want {
+			t.Errorf("anchor of %s = %q, want %q", tt.section.Path, got, tt.want)
+		}
+	}
+}
+
+func TestSymbolIndex(t *testing.T) {
+	if got := symbolIndex(nil); got != "" {
+		t.Errorf("symbolIndex(nil) = %q, want empty", got)
+	}
+
+	got := symbolIndex([]symbol{{Name: "cmd.Execute", Kind: symbolFunc, Path: "cmd/root.go", Line: 44, Anchor: "file-cmdrootgo"}})
+	if !strings.Contains(got, "| `cmd.Execute` | func | [cmd/root.go:44](#file-cmdrootgo) |\n") {
+		t.Errorf("symbolIndex() =\n%s", got)
+	}
+}
@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// defaultTemplate is the built-in Markdown layout used when no template is
+// configured
+//
+//go:embed templates/default.tmpl
+var defaultTemplate string
+
+// templateData is the data model passed to output templates:
+//
+//	.Project      name of the project directory
+//	.Root         absolute path of the project directory
+//	.Generated    time the rollup was generated
+//	.LineNumbers  whether line numbers are enabled
+//	.Files        the included files, in output order (see fileSection)
+//	.Stats        run statistics (see rollupStats)
+//	.Tree         the included files drawn as a directory tree
+//	.Graph        the package dependency graph with order_by: deps
+//	.Symbols      exported Go symbols with symbol_index (see symbol)
+//	.Preamble     rendered preamble text, if configured, after .Graph
+//	.Postamble    rendered postamble text, if configured
+//	.Prompt       the question given with --prompt
+type templateData struct {
+	Project     string
+	Root        string
+	Generated   time.Time
+	LineNumbers bool
+	Files       []*fileSection
+	Stats       *rollupStats
+	Tree        string
+	Graph       string
+	Symbols     []symbol
+	Preamble    string
+	Postamble   string
+	Prompt      string
+}
+
+// fileSection is one included file as seen by output templates
+type fileSection struct {
+	// Path is the slash-separated path relative to the project root
+	Path string
+
+	// Lang is the code block language
+	Lang string
+
+	// Content is the file as it goes into the code block, with line
+	// numbers when they are enabled
+	Content string
+
+	// Source is the unmodified file content
+	Source string
+
+	CodeGenerated bool
+
+	// Module is the path of the Go module holding the file, if any
+	Module string
+
+	// ModuleHeading is set to Module on the first file of each module when
+	// a rollup spans several modules
+	ModuleHeading string
+
+	// Role is what the file is for: source, test, docs, config, build or
+	// fixture
+	Role string
+
+	// SymlinkTarget is set for links listed with symlinks: list; such
+	// sections have no content
+	SymlinkTarget string
+
+	// Summary replaces the code block of manifests and lockfiles rendered
+	// by their handler
+	Summary string
+
+	// Notebook marks a Jupyter notebook whose Content is its conversion
+	// to Markdown
+	Notebook bool
+
+	Size     int64
+	Lines    int
+	Tokens   int
+	Metadata []metadataItem
+
+	// converted is the Markdown of a notebook, before line numbering
+	converted string
+
+	entry *fileEntry
+}
+
+// text returns what a section puts in the rollup: its summary or notebook
+// conversion when it has one, otherwise the file itself
+func (f *fileSection) text() string {
+	switch {
+	case f.Summary != "":
+		return f.Summary
+	case f.Notebook:
+		return f.converted
+	}
+	return f.Source
+}
+
+// templateFuncs are the helper functions available to output templates
+var templateFuncs = template.FuncMap{
+	// fence wraps content in a fenced code block that content cannot close
+	"fence": fencedBlock,
+
+	// metadataTable renders .Metadata as a one-row Markdown table
+	"metadataTable": metadataTable,
+
+	// symbolIndex renders .Symbols as a Markdown table linking each symbol
+	// to its file's section
+	"symbolIndex": symbolIndex,
+
+	// humanSize formats a byte count, e.g. 1.5 KB
+	"humanSize": humanReadableSize,
+
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// loadTemplate parses the template at templatePath, or the built-in
+// Markdown template when templatePath is empty
+func loadTemplate(templatePath string) (*template.Template, error) {
+	name, text := "default", defaultTemplate
+	if templatePath != "" {
+		data, err := os.ReadFile(templatePath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading template: %v", err)
+		}
+		name, text = filepath.Base(templatePath), string(data)
+	}
+
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// renderTemplate executes tmpl over data into w
+func renderTemplate(w io.Writer, tmpl *template.Template, data *templateData) error {
+	if err := tmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("error rendering template: %v", err)
+	}
+	return nil
+}
+
+// renderFraming renders a preamble or postamble setting over data. The
+// setting is either inline text or the path of a file holding the text;
+// both may use the template variables and functions of output templates.
+func renderFraming(name, setting string, data *templateData) (string, error) {
+	if setting == "" {
+		return "", nil
+	}
+	text := setting
+	if !strings.Contains(setting, "\n") {
+		if info, err := os.Stat(setting); err == nil && !info.IsDir() {
+			content, err := os.ReadFile(setting)
+			if err != nil {
+				return "", fmt.Errorf("error reading %s: %v", name, err)
+			}
+			text = string(content)
+		}
+	}
+
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("error parsing %s: %v", name, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("error rendering %s: %v", name, err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// renderTree draws paths as a directory tree rooted at project
+func renderTree(project string, paths []string) string {
+	type node struct {
+		children map[string]*node
+	}
+	root := &node{children: make(map[string]*node)}
+	for _, p := range paths {
+		n := root
+		for _, part := range strings.Split(p, "/") {
+			if n.children[part] == nil {
+				n.children[part] = &node{children: make(map[string]*node)}
+			}
+			n = n.children[part]
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(project + "/\n")
+	var walk func(n *node, prefix string)
+	walk = func(n *node, prefix string) {
+		names := make([]string, 0, len(n.children))
+		for name := range n.children {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for i, name := range names {
+			child := n.children[name]
+			branch, indent := "├── ", "│   "
+			if i == len(names)-1 {
+				branch, indent = "└── ", "    "
+			}
+			if len(child.children) > 0 {
+				name += "/"
+			}
+			b.WriteString(prefix + branch + name + "\n")
+			walk(child, prefix+indent)
+		}
+	}
+	walk(root, "")
+	return b.String()
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderTree(t *testing.T) {
+	result := renderTree("project", []string{"main.go", "cmd/root.go", "cmd/files.go", "internal/config/config.go"})
+	expected := `project/
+├── cmd/
+│   ├── files.go
+│   └── root.go
+├── internal/
+│   └── config/
+│       └── config.go
+└── main.go
+`
+	if result != expected {
+		t.Errorf("renderTree() =\n%s\nwant\n%s", result, expected)
+	}
+}
+
+func TestDefaultTemplate(t *testing.T) {
+	tmpl, err := loadTemplate("")
+	if err != nil {
+		t.Fatalf("loadTemplate() failed: %v", err)
+	}
+
+	data := &templateData{
+		Files: []*fileSection{
+			{Path: "main.go", Lang: "go", Content: "package main\n"},
+			{Path: "gen/api.go", Lang: "go", Content: "package gen", CodeGenerated: true,
+				Metadata: []metadataItem{{"Size", "11 B"}}},
+			{Path: "shared", SymlinkTarget: "../shared"},
+		},
+	}
+	var out bytes.Buffer
+	if err := renderTemplate(&out, tmpl, data); err != nil {
+		t.Fatalf("renderTemplate() failed: %v", err)
+	}
+
+	expected := "# File: main.go\n\n```go\npackage main\n```\n\n" +
+		"# File: gen/api.go (Code-generated, Read-only)\n\n| Size |\n|------|\n| 11 B |\n\n```go\npackage gen\n```\n\n" +
+		"# File: shared (Symlink to ../shared)\n\n"
+	if out.String() != expected {
+		t.Errorf("default template rendered %q; want %q", out.String(), expected)
+	}
+}
+
+func TestCustomTemplate(t *testing.T) {
+	templatePath := filepath.Join(t.TempDir(), "list.tmpl")
+	text := `{{.Project}}: {{len .Files}} files, {{.Stats.FilesIncluded}} included
+{{range .Files}}- {{.Path}} [{{upper .Lang}}] {{humanSize .Size}}
+{{end}}{{.Tree}}`
+	if err := os.WriteFile(templatePath, []byte(text), 0o644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	tmpl, err := loadTemplate(templatePath)
+	if err != nil {
+		t.Fatalf("loadTemplate() failed: %v", err)
+	}
+	stats := newRollupStats()
+	stats.FilesIncluded = 1
+	data := &templateData{
+		Project: "demo",
+		Files:   []*fileSection{{Path: "main.go", Lang: "go", Size: 13}},
+		Stats:   stats,
+		Tree:    renderTree("demo", []string{"main.go"}),
+	}
+	var out bytes.Buffer
+	if err := renderTemplate(&out, tmpl, data); err != nil {
+		t.Fatalf("renderTemplate() failed: %v", err)
+	}
+
+	expected := "demo: 1 files, 1 included\n- main.go [GO] 13 B\ndemo/\n└── main.go\n"
+	if out.String() != expected {
+		t.Errorf("custom template rendered %q; want %q", out.String(), expected)
+	}
+
+	if _, err := loadTemplate(filepath.Join(t.TempDir(), "missing.tmpl")); err == nil {
+		t.Errorf("loadTemplate() expected an error for a missing template")
+	}
+}
+
+func TestRenderFraming(t *testing.T) {
+	data := &templateData{
+		Project: "demo",
+		Files:   []*fileSection{{Path: "main.go"}, {Path: "util.go"}},
+	}
+
+	result, err := renderFraming("preamble", "You are reviewing {{.Project}} ({{len .Files}} files).\n", data)
+	if err != nil {
+		t.Fatalf("renderFraming() failed: %v", err)
+	}
+	if result != "You are reviewing demo (2 files)." {
+		t.Errorf("renderFraming() inline = %q", result)
+	}
+
+	framingPath := filepath.Join(t.TempDir(), "postamble.md")
+	if err := os.WriteFile(framingPath, []byte("Files:{{range .Files}} {{.Path}}{{end}}\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write postamble: %v", err)
+	}
+	result, err = renderFraming("postamble", framingPath, data)
+	if err != nil {
+		t.Fatalf("renderFraming() failed: %v", err)
+	}
+	if result != "Files: main.go util.go" {
+		t.Errorf("renderFraming() from file = %q", result)
+	}
+
+	if _, err := renderFraming("preamble", "{{.Missing", data); err == nil {
+		t.Errorf("renderFraming() expected an error for an invalid template")
+	}
+
+	tmpl, err := loadTemplate("")
+	if err != nil {
+		t.Fatalf("loadTemplate() failed: %v", err)
+	}
+	data.Files = []*fileSection{{Path: "main.go", Lang: "go", Content: "package main\n"}}
+	data.Preamble, data.Postamble, data.Prompt = "Read this.", "That was all.", "What does main do?"
+	var out bytes.Buffer
+	if err := renderTemplate(&out, tmpl, data); err != nil {
+		t.Fatalf("renderTemplate() failed: %v", err)
+	}
+	expected := "Read this.\n\n# File: main.go\n\n```go\npackage main\n```\n\nThat was all.\n\nWhat does main do?\n"
+	if out.String() != expected {
+		t.Errorf("default template with framing rendered %q; want %q", out.String(), expected)
+	}
+}
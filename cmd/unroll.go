@@ -0,0 +1,478 @@
+package cmd
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	unrollInto   string
+	unrollDryRun bool
+)
+
+var unrollCmd = &cobra.Command{
+	Use:   "unroll <rollup>",
+	Short: "Write the files in a rollup back to a directory",
+	Long: `Unroll parses the file sections of a rollup made by the files subcommand and writes
+each file back under the target directory. The rollup may be a single .rollup.md file
+or a directory written with --output=separate. Line numbers, metadata tables and
+notes added to section headers are removed again.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runUnroll(args[0], unrollInto)
+	},
+}
+
+func init() {
+	unrollCmd.Flags().StringVar(&unrollInto, "into", "", "Directory to write the files into")
+	unrollCmd.Flags().BoolVar(&unrollDryRun, "dry-run", false, "Show a diff of the changes instead of writing them")
+	unrollCmd.MarkFlagRequired("into")
+}
+
+// unrolledFile is one file section parsed from a rollup
+type unrolledFile struct {
+	Path          string
+	Content       string
+	SymlinkTarget string
+	CodeGenerated bool
+
+	// Summary marks a manifest or lockfile rolled up as a summary, whose
+	// Content is the summary rather than the file
+	Summary bool
+
+	// Notebook marks a Jupyter notebook rolled up as Markdown
+	Notebook bool
+}
+
+// Markers written by the default template
+const (
+	headerPrefix   = "# File: "
+	modulePrefix   = "# Module: "
+	codeGenSuffix  = " (Code-generated, Read-only)"
+	summarySuffix  = " (Summary)"
+	notebookSuffix = " (Notebook)"
+)
+
+var (
+	symlinkSuffix   = regexp.MustCompile(` \(Symlink to (.*)\)$`)
+	linesSuffix     = regexp.MustCompile(` \(\d+ lines\)$`)
+	openingFence    = regexp.MustCompile("^(`{3,})[^`]*$")
+	lineNumberStrip = regexp.MustCompile(`^ *\d+ \|(?: |$)`)
+)
+
+// parseRollup extracts the file sections from rollup content in the order
+// they appear. Text outside sections, such as a preamble, is ignored, but
+// a summary runs up to the next header and so takes in any text after it.
+func parseRollup(content string) ([]*unrolledFile, error) {
+	var files []*unrolledFile
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	// pending is the section whose header has been read but whose code
+	// block has not started yet
+	var pending *unrolledFile
+	var numbered bool
+	var metadata []string
+
+	// summary is the summary section being read and summaryFence is set
+	// while inside one of its code blocks. Its text runs up to the next file
+	// or module header.
+	var summary *unrolledFile
+	var summaryFence string
+	endSummary := func() {
+		if summary != nil && summary.Content != "" {
+			summary.Content = strings.TrimRight(summary.Content, "\n") + "\n"
+		}
+		summary = nil
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if summaryFence != "" {
+			summary.Content += line + "\n"
+			if strings.Trim(line, "`") == "" && len(line) >= len(summaryFence) {
+				summaryFence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(line, modulePrefix) {
+			endSummary()
+			continue
+		}
+		if strings.HasPrefix(line, headerPrefix) {
+			if pending != nil && pending.SymlinkTarget == "" && !pending.Summary {
+				return nil, fmt.Errorf("no code block for %s", pending.Path)
+			}
+			endSummary()
+			pending, numbered = parseHeader(strings.TrimPrefix(line, headerPrefix))
+			metadata = nil
+			if pending.Summary {
+				summary = pending
+			}
+			if pending.SymlinkTarget != "" || pending.Summary {
+				files = append(files, pending)
+			}
+			continue
+		}
+		if summary != nil {
+			if summary.Content == "" && strings.TrimSpace(line) == "" {
+				continue
+			}
+			summary.Content += line + "\n"
+			if m := openingFence.FindStringSubmatch(line); m != nil {
+				summaryFence = m[1]
+			}
+			continue
+		}
+		if pending == nil || pending.SymlinkTarget != "" || pending.Summary {
+			continue
+		}
+		if strings.HasPrefix(line, "|") {
+			metadata = append(metadata, line)
+			continue
+		}
+		m := openingFence.FindStringSubmatch(line)
+		if m == nil {
+			if strings.TrimSpace(line) != "" {
+				return nil, fmt.Errorf("unexpected text before the code block of %s: %q", pending.Path, line)
+			}
+			continue
+		}
+
+		// Read the block up to its closing fence, which is never shorter
+		// than the opening one
+		var b strings.Builder
+		closed := false
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.Trim(line, "`") == "" && len(line) >= len(m[1]) {
+				closed = true
+				break
+			}
+			if numbered {
+				line = lineNumberStrip.ReplaceAllString(line, "")
+			}
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+		if !closed {
+			return nil, fmt.Errorf("unterminated code block for %s", pending.Path)
+		}
+
+		pending.Content = restoreFinalNewline(b.String(), metadataValue(metadata, "SHA-256"))
+		files = append(files, pending)
+		pending = nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	endSummary()
+	if pending != nil && pending.SymlinkTarget == "" && !pending.Summary {
+		return nil, fmt.Errorf("no code block for %s", pending.Path)
+	}
+	return files, nil
+}
+
+// parseHeader splits a section header into the file it names and whether
+// its code block has line numbers
+func parseHeader(header string) (*unrolledFile, bool) {
+	file := &unrolledFile{}
+	if m := symlinkSuffix.FindStringSubmatchIndex(header); m != nil {
+		file.SymlinkTarget = header[m[2]:m[3]]
+		header = header[:m[0]]
+	}
+	header, file.Summary = strings.CutSuffix(header, summarySuffix)
+	numbered := linesSuffix.MatchString(header)
+	header = linesSuffix.ReplaceAllString(header, "")
+	header, file.Notebook = strings.CutSuffix(header, notebookSuffix)
+	file.Path, file.CodeGenerated = strings.CutSuffix(header, codeGenSuffix)
+	return file, numbered
+}
+
+// metadataValue returns the named column of a metadata table, or "" when
+// the table has no such column
+func metadataValue(table []string, name string) string {
+	if len(table) < 3 {
+		return ""
+	}
+	names := strings.Split(strings.Trim(table[0], "|"), "|")
+	values := strings.Split(strings.Trim(table[2], "|"), "|")
+	for i, n := range names {
+		if strings.TrimSpace(n) == name && i < len(values) {
+			return strings.Trim(strings.TrimSpace(values[i]), "`")
+		}
+	}
+	return ""
+}
+
+// restoreFinalNewline undoes the newline added to close the code block of
+// a file that did not end with one. Rollups only record this through the
+// SHA-256 metadata; without it every file is taken to end with a newline.
+func restoreFinalNewline(content, sum string) string {
+	if sum == "" || content == "" || hashContent(content) == sum {
+		return content
+	}
+	if trimmed := strings.TrimSuffix(content, "\n"); hashContent(trimmed) == sum {
+		return trimmed
+	}
+	return content
+}
+
+func hashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// readRollup parses a rollup file, or every rollup in a directory written
+// with output_type: separate
+func readRollup(source string) ([]*unrolledFile, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+	sources := []string{source}
+	if info.IsDir() {
+		sources, err = filepath.Glob(filepath.Join(source, "*.rollup.md"))
+		if err != nil {
+			return nil, err
+		}
+		if len(sources) == 0 {
+			return nil, fmt.Errorf("no rollup files in %s", source)
+		}
+	}
+
+	var files []*unrolledFile
+	seen := make(map[string]string)
+	for _, src := range sources {
+		content, err := os.ReadFile(src)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := parseRollup(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", src, err)
+		}
+		for _, f := range parsed {
+			if prev, ok := seen[f.Path]; ok {
+				return nil, fmt.Errorf("%s: %s already appears in %s", src, f.Path, prev)
+			}
+			seen[f.Path] = src
+		}
+		files = append(files, parsed...)
+	}
+	return files, nil
+}
+
+// unrollTarget returns where a file from a rollup is written under into,
+// refusing any path that would end up outside it
+func unrollTarget(into, name string) (string, error) {
+	rel := filepath.FromSlash(name)
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("refusing to write %s: it is outside %s", name, into)
+	}
+	target := filepath.Join(into, rel)
+
+	// A symlink already in the target directory could still lead outside
+	root, err := resolveExisting(into)
+	if err != nil {
+		return "", err
+	}
+	dir, err := resolveExisting(filepath.Dir(target))
+	if err != nil {
+		return "", err
+	}
+	if !withinRoot(root, dir) {
+		return "", fmt.Errorf("refusing to write %s: its directory leads outside %s", name, into)
+	}
+	return target, nil
+}
+
+// withinRoot reports whether the resolved path p is root or below it
+func withinRoot(root, p string) bool {
+	rel, err := filepath.Rel(root, p)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+// checkLink refuses a link just created at target whose destination
+// resolves outside into. Links to files that do not exist yet cannot be
+// resolved; writes through them are refused by unrollTarget instead.
+func checkLink(into, name, target string) error {
+	root, err := resolveExisting(into)
+	if err != nil {
+		return err
+	}
+	resolved, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		return nil
+	}
+	if !withinRoot(root, resolved) {
+		return fmt.Errorf("refusing to link %s: it leads outside %s", name, into)
+	}
+	return nil
+}
+
+// resolveExisting resolves the symlinks in the deepest existing ancestor of
+// p and appends the rest of p to it
+func resolveExisting(p string) (string, error) {
+	p, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+	var rest []string
+	for {
+		if _, err := os.Lstat(p); err == nil {
+			break
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			break
+		}
+		rest = append([]string{filepath.Base(p)}, rest...)
+		p = parent
+	}
+	resolved, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append([]string{resolved}, rest...)...), nil
+}
+
+func runUnroll(source, into string) error {
+	files, err := readRollup(source)
+	if err != nil {
+		return err
+	}
+
+	// Check every path before writing anything, so a bad rollup leaves the
+	// target untouched
+	targets := make([]string, len(files))
+	for i, f := range files {
+		if f.Summary || f.Notebook {
+			continue
+		}
+		if targets[i], err = unrollTarget(into, f.Path); err != nil {
+			return err
+		}
+		if f.SymlinkTarget == "" {
+			if info, err := os.Lstat(targets[i]); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				return fmt.Errorf("refusing to write %s: it is a symlink", f.Path)
+			}
+		} else {
+			linked := filepath.Join(filepath.Dir(f.Path), filepath.FromSlash(f.SymlinkTarget))
+			if filepath.IsAbs(f.SymlinkTarget) || !filepath.IsLocal(linked) {
+				return fmt.Errorf("refusing to link %s to %s: it is outside %s", f.Path, f.SymlinkTarget, into)
+			}
+		}
+	}
+
+	var created, modified, unchanged, summarized int
+	for i, f := range files {
+		// Summaries of manifests and lockfiles and converted notebooks
+		// cannot be turned back into the files
+		if f.Summary || f.Notebook {
+			summarized++
+			if verbose {
+				fmt.Printf("Skipping %s: it was rolled up as a summary or conversion\n", f.Path)
+			}
+			continue
+		}
+		target := targets[i]
+		existing, err := os.ReadFile(target)
+		exists := err == nil
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		if f.SymlinkTarget != "" {
+			if _, err := os.Lstat(target); err == nil {
+				if verbose {
+					fmt.Printf("Skipping existing %s\n", f.Path)
+				}
+				unchanged++
+				continue
+			}
+			created++
+			if unrollDryRun {
+				fmt.Printf("Symlink %s -> %s\n", f.Path, f.SymlinkTarget)
+				continue
+			}
+			// Links created earlier in this run may have changed where the
+			// target's directory leads
+			if _, err := unrollTarget(into, f.Path); err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := os.Symlink(f.SymlinkTarget, target); err != nil {
+				return err
+			}
+			// A target that looks local can still lead outside through
+			// other links, e.g. a link to "l2/.." where l2 links to "."
+			if err := checkLink(into, f.Path, target); err != nil {
+				os.Remove(target)
+				return err
+			}
+			continue
+		}
+
+		switch {
+		case exists && string(existing) == f.Content:
+			unchanged++
+			if verbose {
+				fmt.Printf("Unchanged %s\n", f.Path)
+			}
+			continue
+		case exists:
+			modified++
+			if unrollDryRun {
+				fmt.Print(unifiedDiff("a/"+f.Path, "b/"+f.Path, string(existing), f.Content))
+			}
+		default:
+			created++
+			if unrollDryRun {
+				fmt.Print(unifiedDiff("/dev/null", "b/"+f.Path, "", f.Content))
+			}
+		}
+		if unrollDryRun {
+			continue
+		}
+
+		// Check again right before writing, as links created in this run
+		// may lead the target elsewhere
+		if _, err := unrollTarget(into, f.Path); err != nil {
+			return err
+		}
+		if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("refusing to write %s: it is a symlink", f.Path)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, []byte(f.Content), 0644); err != nil {
+			return err
+		}
+		if verbose {
+			fmt.Printf("Wrote %s\n", f.Path)
+		}
+	}
+
+	summary := fmt.Sprintf("%d new, %d modified, %d unchanged", created, modified, unchanged)
+	if unrollDryRun {
+		fmt.Printf("Dry run: would write %s files in %s\n", summary, into)
+	} else {
+		fmt.Printf("Unroll complete: %s files in %s\n", summary, into)
+	}
+	if summarized > 0 {
+		fmt.Printf("Skipped %d files rolled up as summaries or conversions\n", summarized)
+	}
+	return nil
+}
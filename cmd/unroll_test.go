@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseRollup(t *testing.T) {
+	rollup := "Preamble text\n\n" +
+		"# File: main.go\n\n```go\npackage main\n```\n\n" +
+		"# File: gen/api.go (Code-generated, Read-only)\n\n```go\npackage gen\n```\n\n" +
+		"# File: docs/guide.md (3 lines)\n\n" +
+		"| SHA-256 |\n|---------|\n| `" + hashContent("a\n\nb") + "` |\n\n" +
+		"````markdown\n1 | a\n2 |\n3 | b\n````\n\n" +
+		"# File: go.mod (Summary)\n\nModule `x`\n\n| Dependency |\n|---|\n| `y` |\n\n" +
+		"# File: link.go (Symlink to main.go)\n\n" +
+		"# File: nb.ipynb (Notebook)\n\n````markdown\n# Title\n\n```python\nx = 1\n```\n````\n\n" +
+		"# File: go.sum (Summary)\n\nLockfile with 2 entries.\n\n" +
+		"# File: data.csv (Summary)\n\nCSV file of 1 MB.\n\n```csv\na,b\n\n# File: x\n```\n\n" +
+		"# File: last.go\n\n```go\npackage last\n```\n\n" +
+		"Postamble text\n"
+
+	files, err := parseRollup(rollup)
+	if err != nil {
+		t.Fatalf("parseRollup() returned error: %v", err)
+	}
+
+	want := []unrolledFile{
+		{Path: "main.go", Content: "package main\n"},
+		{Path: "gen/api.go", Content: "package gen\n", CodeGenerated: true},
+		{Path: "docs/guide.md", Content: "a\n\nb"},
+		{Path: "go.mod", Content: "Module `x`\n\n| Dependency |\n|---|\n| `y` |\n", Summary: true},
+		{Path: "link.go", SymlinkTarget: "main.go"},
+		{Path: "nb.ipynb", Content: "# Title\n\n```python\nx = 1\n```\n", Notebook: true},
+		{Path: "go.sum", Content: "Lockfile with 2 entries.\n", Summary: true},
+		{Path: "data.csv", Content: "CSV file of 1 MB.\n\n```csv\na,b\n\n# File: x\n```\n", Summary: true},
+		{Path: "last.go", Content: "package last\n"},
+	}
+	if len(files) != len(want) {
+		t.Fatalf("parseRollup() returned %d files, want %d", len(files), len(want))
+	}
+	for i, f := range files {
+		if *f != want[i] {
+			t.Errorf("file %d = %+v, want %+v", i, *f, want[i])
+		}
+	}
+}
+
+func TestParseRollupSummaryRoundTrip(t *testing.T) {
+	tmpl, err := loadTemplate("")
+	if err != nil {
+		t.Fatalf("loadTemplate() failed: %v", err)
+	}
+	summary := manifestTable("Module `example.com/app`, Go 1.23", nil)
+	data := &templateData{
+		Files: []*fileSection{
+			{Path: "go.mod", Module: "example.com/app", ModuleHeading: "example.com/app", Summary: summary},
+			{Path: "tools/go.mod", Module: "example.com/tools", ModuleHeading: "example.com/tools", Summary: manifestTable("", nil)},
+			{Path: "tools/main.go", Module: "example.com/tools", Lang: "go", Content: "package main\n"},
+		},
+	}
+	var out bytes.Buffer
+	if err := renderTemplate(&out, tmpl, data); err != nil {
+		t.Fatalf("renderTemplate() failed: %v", err)
+	}
+
+	// The blank line between the title and "No dependencies." does not end
+	// the summary; the next header does
+	files, err := parseRollup(out.String())
+	if err != nil {
+		t.Fatalf("parseRollup() returned error: %v", err)
+	}
+	want := []unrolledFile{
+		{Path: "go.mod", Content: summary, Summary: true},
+		{Path: "tools/go.mod", Content: "No dependencies.\n", Summary: true},
+		{Path: "tools/main.go", Content: "package main\n"},
+	}
+	if len(files) != len(want) {
+		t.Fatalf("parseRollup() returned %d files, want %d", len(files), len(want))
+	}
+	for i, f := range files {
+		if *f != want[i] {
+			t.Errorf("file %d = %+v, want %+v", i, *f, want[i])
+		}
+	}
+}
+
+func TestParseRollupErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		rollup string
+	}{
+		{"unterminated block", "# File: a.go\n\n```go\npackage a\n"},
+		{"missing block", "# File: a.go\n\n# File: b.go\n\n```go\n```\n"},
+		{"stray text", "# File: a.go\n\nsurprise\n```go\n```\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseRollup(tt.rollup); err == nil {
+				t.Errorf("parseRollup() returned no error")
+			}
+		})
+	}
+}
+
+func TestRunUnroll(t *testing.T) {
+	tempDir := t.TempDir()
+	into := filepath.Join(tempDir, "out")
+	writeTree(t, into, map[string]string{"keep.go": "package old\n"})
+
+	writeRollup := func(content string) string {
+		source := filepath.Join(tempDir, "in.rollup.md")
+		if err := os.WriteFile(source, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write rollup: %v", err)
+		}
+		return source
+	}
+
+	source := writeRollup("# File: keep.go\n\n```go\npackage keep\n```\n\n# File: sub/new.go\n\n```go\npackage sub\n```\n\n" +
+		"# File: go.sum (Summary)\n\nLockfile with 1 entries.\n")
+
+	unrollDryRun = true
+	if err := runUnroll(source, into); err != nil {
+		t.Fatalf("runUnroll() dry run returned error: %v", err)
+	}
+	unrollDryRun = false
+	if _, err := os.Stat(filepath.Join(into, "sub")); !os.IsNotExist(err) {
+		t.Errorf("dry run wrote files")
+	}
+
+	if err := runUnroll(source, into); err != nil {
+		t.Fatalf("runUnroll() returned error: %v", err)
+	}
+	for name, want := range map[string]string{"keep.go": "package keep\n", "sub/new.go": "package sub\n"} {
+		got, err := os.ReadFile(filepath.Join(into, name))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(into, "go.sum")); !os.IsNotExist(err) {
+		t.Errorf("runUnroll() wrote a file rolled up as a summary")
+	}
+
+	for _, name := range []string{"../escape.go", "/etc/escape.go", "sub/../../escape.go"} {
+		source := writeRollup("# File: " + name + "\n\n```go\n```\n")
+		err := runUnroll(source, into)
+		if err == nil || !strings.Contains(err.Error(), "refusing") {
+			t.Errorf("runUnroll() for %s: error = %v, want refusal", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "escape.go")); !os.IsNotExist(err) {
+		t.Errorf("runUnroll() wrote outside the target directory")
+	}
+
+	// A symlink inside the target must not lead writes outside it
+	if err := os.Symlink(tempDir, filepath.Join(into, "link")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	source = writeRollup("# File: link/escape.go\n\n```go\n```\n")
+	if err := runUnroll(source, into); err == nil {
+		t.Errorf("runUnroll() wrote through a symlink leading outside the target")
+	}
+}
+
+func TestRunUnrollChainedSymlinks(t *testing.T) {
+	tempDir := t.TempDir()
+	into := filepath.Join(tempDir, "out")
+	if err := os.MkdirAll(into, 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.Symlink(".", filepath.Join(tempDir, "probe")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	// Each link target looks local on its own, but l1 leads to the parent
+	// of the target directory through l2
+	source := filepath.Join(tempDir, "r.rollup.md")
+	writeTree(t, tempDir, map[string]string{
+		"r.rollup.md": "# File: l2 (Symlink to .)\n\n" +
+			"# File: l1 (Symlink to l2/..)\n\n" +
+			"# File: l1/evil.txt\n\n```text\nescaped\n```\n",
+	})
+
+	if err := runUnroll(source, into); err == nil || !strings.Contains(err.Error(), "refusing") {
+		t.Errorf("runUnroll() error = %v, want refusal", err)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "evil.txt")); !os.IsNotExist(err) {
+		t.Errorf("runUnroll() wrote outside the target directory through chained links")
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Symlink policies for the files command
+const (
+	symlinksSkip   = "skip"
+	symlinksFollow = "follow"
+	symlinksList   = "list"
+)
+
+// walkTree walks the tree rooted at root like filepath.Walk, applying the
+// symlink policy to every link it meets. With "follow", linked files and
+// directories are visited as if they were regular entries; each directory
+// is entered at most once, keyed by device and inode, so link cycles cannot
+// recurse forever; broken links are passed to walkFn unresolved. With
+// "list" and "skip", every link is passed to walkFn unresolved, so walkFn
+// can list or record it, and never followed.
+func walkTree(root, policy string, walkFn filepath.WalkFunc) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return walkFn(root, nil, err)
+	}
+	visited := make(map[string]bool)
+	err = walkEntry(root, info, policy, visited, walkFn)
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func walkEntry(path string, info os.FileInfo, policy string, visited map[string]bool, walkFn filepath.WalkFunc) error {
+	if !info.IsDir() {
+		return walkFn(path, info, nil)
+	}
+
+	key := fileKey(path, info)
+	if visited[key] {
+		if verbose {
+			fmt.Printf("Skipping already visited directory: %s\n", path)
+		}
+		return nil
+	}
+	visited[key] = true
+
+	entries, readErr := os.ReadDir(path)
+	if err := walkFn(path, info, readErr); err != nil || readErr != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		entryPath := filepath.Join(path, entry.Name())
+		entryInfo, err := os.Lstat(entryPath)
+		if err != nil {
+			if err := walkFn(entryPath, nil, err); err != nil && err != filepath.SkipDir {
+				return err
+			}
+			continue
+		}
+
+		if isSymlink(entryInfo) {
+			target, err := os.Stat(entryPath)
+			if policy != symlinksFollow || err != nil {
+				if err := walkFn(entryPath, entryInfo, nil); err != nil && err != filepath.SkipDir {
+					return err
+				}
+				continue
+			}
+			entryInfo = target
+		}
+
+		if err := walkEntry(entryPath, entryInfo, policy, visited, walkFn); err != nil {
+			if err == filepath.SkipDir {
+				if entryInfo.IsDir() {
+					continue
+				}
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// isSymlink reports whether info describes an unresolved symbolic link
+func isSymlink(info os.FileInfo) bool {
+	return info.Mode()&os.ModeSymlink != 0
+}
+
+// resolvedPath returns path with every symlink resolved, or the cleaned
+// absolute path when resolution fails.
+func resolvedPath(path string) string {
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		path = real
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// isDirLink reports whether the symlink at path points to a directory
+func isDirLink(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
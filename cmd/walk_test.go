@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestWalkTreeSymlinks(t *testing.T) {
+	tempDir := t.TempDir()
+	root := filepath.Join(tempDir, "project")
+	shared := filepath.Join(tempDir, "shared")
+
+	writeTree(t, tempDir, map[string]string{"project/pkg/a.go": "x\n", "shared/config.yml": "x\n"})
+
+	links := map[string]string{
+		filepath.Join(root, "shared"):      shared,
+		filepath.Join(root, "pkg", "loop"): root,
+		filepath.Join(root, "link.go"):     filepath.Join(root, "pkg", "a.go"),
+		filepath.Join(root, "broken.go"):   filepath.Join(root, "missing.go"),
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("Symlinks are not supported: %v", err)
+		}
+	}
+
+	tests := []struct {
+		policy string
+		want   []string
+	}{
+		{symlinksSkip, []string{"broken.go", "link.go", "pkg/a.go", "pkg/loop", "shared"}},
+		{symlinksFollow, []string{"broken.go", "link.go", "pkg/a.go", "shared/config.yml"}},
+		{symlinksList, []string{"broken.go", "link.go", "pkg/a.go", "pkg/loop", "shared"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			var got []string
+			err := walkTree(root, tt.policy, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if !info.IsDir() {
+					rel, _ := filepath.Rel(root, path)
+					got = append(got, filepath.ToSlash(rel))
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("walkTree() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("walkTree(%q) visited %v; want %v", tt.policy, got, tt.want)
+			}
+		})
+	}
+
+	// Links are only followed when asked for
+	for _, c := range []*cobra.Command{filesCmd, explainCmd} {
+		if got := c.Flags().Lookup("symlinks").DefValue; got != symlinksSkip {
+			t.Errorf("%s --symlinks defaults to %q; want %q", c.Name(), got, symlinksSkip)
+		}
+	}
+}
@@ -2,7 +2,10 @@ package config
 
 import (
 	"fmt"
+	"math"
 	"os"
+	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v2"
 )
@@ -18,12 +21,117 @@ type Config struct {
 	// CodeGeneratedPaths is a list of glob patterns for code-generated files
 	CodeGeneratedPaths []string `yaml:"code_generated_paths"`
 
+	// CaseInsensitive matches ignore, include and code-generated patterns
+	// without regard to case
+	CaseInsensitive bool `yaml:"case_insensitive,omitempty"`
+
+	// IncludeHidden includes dotfiles and dot-directories other than .git
+	IncludeHidden bool `yaml:"include_hidden,omitempty"`
+
+	// IncludePaths is a list of glob patterns for hidden paths to include
+	// even when IncludeHidden is false
+	IncludePaths []string `yaml:"include_paths,omitempty"`
+
+	// Symlinks sets how symlinks are handled: "skip", "follow" or "list"
+	Symlinks string `yaml:"symlinks,omitempty"`
+
+	// LineNumbers prefixes each line in code blocks with its line number
+	LineNumbers bool `yaml:"line_numbers,omitempty"`
+
+	// MaxFileSize skips files larger than this size, e.g. "512KB" or "2MB"
+	MaxFileSize string `yaml:"max_file_size,omitempty"`
+
+	// FileMetadata lists the metadata shown under each file header: size,
+	// lines, tokens, sha256, mtime and git
+	FileMetadata []string `yaml:"file_metadata,omitempty"`
+
+	// Template is the path to a text/template file used to render file
+	// rollups instead of the built-in Markdown layout
+	Template string `yaml:"template,omitempty"`
+
+	// Preamble is text, or the path of a file holding text, placed before
+	// the files of a rollup. It may use output template variables.
+	Preamble string `yaml:"preamble,omitempty"`
+
+	// Postamble is text, or the path of a file holding text, placed after
+	// the files of a rollup. It may use output template variables.
+	Postamble string `yaml:"postamble,omitempty"`
+
+	// LanguageMap overrides the code block language for a file name or an
+	// extension (without leading dot)
+	LanguageMap map[string]string `yaml:"language_map,omitempty"`
+
+	// FileHandlers sets how a file name is rendered: "raw", "manifest" for
+	// a dependency table, "summary" for a one-line summary or "notebook"
+	// to convert a Jupyter notebook to Markdown. Keys are file names or
+	// extensions (without leading dot).
+	FileHandlers map[string]string `yaml:"file_handlers,omitempty"`
+
+	// NotebookOutputs includes the outputs of notebook code cells
+	NotebookOutputs bool `yaml:"notebook_outputs,omitempty"`
+
+	// DataThreshold is the size from which CSV and TSV files are sampled
+	// and JSON and YAML files summarized, e.g. "64KB"
+	DataThreshold string `yaml:"data_threshold,omitempty"`
+
+	// SampleRows and RandomRows are the leading and random rows shown of
+	// sampled files
+	SampleRows *int `yaml:"sample_rows,omitempty"`
+	RandomRows *int `yaml:"random_rows,omitempty"`
+
+	// Roles limits file rollups to files with these roles: source, test,
+	// docs, config, build and fixture
+	Roles []string `yaml:"roles,omitempty"`
+
+	// Tests places test files "adjacent" to their source file, at the
+	// "end" of the rollup, or "omit"s them
+	Tests string `yaml:"tests,omitempty"`
+
+	// SymbolIndex starts file rollups with an index of the exported Go
+	// types, functions and methods and where they are declared
+	SymbolIndex bool `yaml:"symbol_index,omitempty"`
+
+	// OrderBy is the order files are emitted in: "path", or "deps" to put
+	// each Go package after the packages it imports
+	OrderBy string `yaml:"order_by,omitempty"`
+
+	// Modules limits file rollups to the Go modules with these paths; a
+	// path ending in /... also selects the modules below it
+	Modules []string `yaml:"modules,omitempty"`
+
+	// Focus lists Go files or package directories; when set, only the
+	// packages they import within their module are rolled up
+	Focus []string `yaml:"focus,omitempty"`
+
+	// FocusDepth limits how many imports are followed from Focus; 0 means
+	// no limit
+	FocusDepth int `yaml:"focus_depth,omitempty"`
+
+	// FocusTests includes the test files of focused packages
+	FocusTests bool `yaml:"focus_tests,omitempty"`
+
+	// ChunkBy is the unit of ChunkSize and ChunkOverlap for --chunks:
+	// "lines" or "tokens"
+	ChunkBy string `yaml:"chunk_by,omitempty"`
+
+	// ChunkSize is the largest chunk written by --chunks
+	ChunkSize int `yaml:"chunk_size,omitempty"`
+
+	// ChunkOverlap is how much of each chunk is repeated at the start of
+	// the next
+	ChunkOverlap *int `yaml:"chunk_overlap,omitempty"`
+
 	// Sites is a list of site configurations for web scraping
 	Sites []SiteConfig `yaml:"sites"`
 
 	// OutputType specifies how the output should be generated
 	OutputType string `yaml:"output_type"`
 
+	// SplitDepth sets how many directory levels group files into separate
+	// rollups when OutputType is "separate"; 0 groups by each file's
+	// directory, i.e. per Go package
+	SplitDepth *int `yaml:"split_depth,omitempty"`
+
 	// RequestsPerSecond limits the rate of web requests
 	RequestsPerSecond *float64 `yaml:"requests_per_second,omitempty"`
 
@@ -86,6 +194,23 @@ func Load(configPath string) (*Config, error) {
 	return &config, nil
 }
 
+// LoadFragment reads a nested .rollup.yml file. Fragments only adjust the
+// settings for their own subtree, so they are not validated as a full
+// configuration.
+func LoadFragment(fragmentPath string) (*Config, error) {
+	data, err := os.ReadFile(fragmentPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config fragment: %v", err)
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("error parsing config fragment: %v", err)
+	}
+
+	return &config, nil
+}
+
 // Validate checks the configuration for any invalid values
 func (c *Config) Validate() error {
 	if len(c.FileExtensions) == 0 && len(c.Sites) == 0 {
@@ -96,6 +221,50 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("output_type must be 'single' or 'separate'")
 	}
 
+	if c.Symlinks != "" && c.Symlinks != "skip" && c.Symlinks != "follow" && c.Symlinks != "list" {
+		return fmt.Errorf("symlinks must be 'skip', 'follow' or 'list'")
+	}
+
+	if _, err := ParseSize(c.MaxFileSize); err != nil {
+		return fmt.Errorf("max_file_size: %v", err)
+	}
+
+	if _, err := ParseSize(c.DataThreshold); err != nil {
+		return fmt.Errorf("invalid data_threshold: %v", err)
+	}
+
+	if (c.SampleRows != nil && *c.SampleRows < 0) || (c.RandomRows != nil && *c.RandomRows < 0) {
+		return fmt.Errorf("sample_rows and random_rows must not be negative")
+	}
+
+	if c.Tests != "" && c.Tests != "adjacent" && c.Tests != "end" && c.Tests != "omit" {
+		return fmt.Errorf("tests must be 'adjacent', 'end' or 'omit'")
+	}
+
+	if c.OrderBy != "" && c.OrderBy != "path" && c.OrderBy != "deps" {
+		return fmt.Errorf("order_by must be 'path' or 'deps'")
+	}
+
+	if c.FocusDepth < 0 {
+		return fmt.Errorf("focus_depth must not be negative")
+	}
+
+	if c.ChunkBy != "" && c.ChunkBy != "lines" && c.ChunkBy != "tokens" {
+		return fmt.Errorf("chunk_by must be 'lines' or 'tokens'")
+	}
+
+	if c.ChunkSize < 0 {
+		return fmt.Errorf("chunk_size must not be negative")
+	}
+
+	if c.ChunkOverlap != nil && *c.ChunkOverlap < 0 {
+		return fmt.Errorf("chunk_overlap must not be negative")
+	}
+
+	if c.SplitDepth != nil && *c.SplitDepth < 0 {
+		return fmt.Errorf("split_depth must not be negative")
+	}
+
 	if c.RequestsPerSecond != nil && *c.RequestsPerSecond <= 0 {
 		return fmt.Errorf("requests_per_second must be positive")
 	}
@@ -112,3 +281,35 @@ func (c *Config) Validate() error {
 
 	return nil
 }
+
+// ParseSize parses a size such as "2048", "512KB", "1.5MB" or "1GB" into
+// bytes, using 1024-byte units. An empty string means no limit and parses
+// as 0; negative, non-finite and overflowing sizes are rejected.
+func ParseSize(size string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	if s == "" {
+		return 0, nil
+	}
+
+	multiplier := int64(1)
+	for i, unit := range []string{"KB", "MB", "GB", "TB"} {
+		if strings.HasSuffix(s, unit) {
+			multiplier = int64(1) << (10 * (i + 1))
+			s = strings.TrimSuffix(s, unit)
+			break
+		}
+	}
+	if multiplier == 1 {
+		s = strings.TrimSuffix(s, "B")
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	bytes := n * float64(multiplier)
+	if bytes >= math.MaxInt64 {
+		return 0, fmt.Errorf("size %q is too large", size)
+	}
+	return int64(bytes), nil
+}
@@ -152,6 +152,78 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Valid symlink policy",
+			config: Config{
+				FileExtensions: []string{"go"},
+				Symlinks:       "list",
+			},
+			wantErr: false,
+		},
+		{
+			name: "Invalid symlink policy",
+			config: Config{
+				FileExtensions: []string{"go"},
+				Symlinks:       "resolve",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Valid file metadata",
+			config: Config{
+				FileExtensions: []string{"go"},
+				FileMetadata:   []string{"size", "sha256", "git"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Valid max file size",
+			config: Config{
+				FileExtensions: []string{"go"},
+				MaxFileSize:    "1.5MB",
+			},
+			wantErr: false,
+		},
+		{
+			name: "Invalid max file size",
+			config: Config{
+				FileExtensions: []string{"go"},
+				MaxFileSize:    "large",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid data threshold",
+			config: Config{
+				FileExtensions: []string{"go"},
+				DataThreshold:  "lots",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid chunk unit",
+			config: Config{
+				FileExtensions: []string{"go"},
+				ChunkBy:        "words",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Negative chunk overlap",
+			config: Config{
+				FileExtensions: []string{"go"},
+				ChunkOverlap:   func() *int { i := -1; return &i }(),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Negative split depth",
+			config: Config{
+				FileExtensions: []string{"go"},
+				SplitDepth:     func() *int { i := -1; return &i }(),
+			},
+			wantErr: true,
+		},
 		{
 			name: "Invalid output type",
 			config: Config{
@@ -171,3 +243,37 @@ func TestValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		size    string
+		want    int64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"2048", 2048, false},
+		{"100B", 100, false},
+		{"512KB", 512 * 1024, false},
+		{"1.5mb", 1536 * 1024, false},
+		{"2 GB", 2 << 30, false},
+		{"ten", 0, true},
+		{"-1KB", 0, true},
+		{"-0.5", 0, true},
+		{"NaN", 0, true},
+		{"inf", 0, true},
+		{"+InfKB", 0, true},
+		{"-Infinity", 0, true},
+		{"1e30TB", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSize(tt.size)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSize(%q) error = %v, wantErr %v", tt.size, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSize(%q) = %d; want %d", tt.size, got, tt.want)
+		}
+	}
+}